@@ -1,4 +1,4 @@
-import { useState, useEffect } from 'react';
+import { useState, useEffect, useCallback } from 'react';
 import { Button, Container, Stack, Paper, Typography, ToggleButtonGroup, ToggleButton } from '@mui/material';
 import { DateTimePicker } from '@mui/x-date-pickers/DateTimePicker';
 import { LocalizationProvider } from '@mui/x-date-pickers/LocalizationProvider';
@@ -7,6 +7,8 @@ import { Team, Player, GamePlayer, Role } from '../types';
 import { TeamSelection } from './TeamSelection';
 import { gameService } from '../services/gameService';
 import { playerService } from '../services/playerService';
+import { auth, AuthError } from '../services/auth';
+import { TelegramLogin } from './TelegramLogin';
 import dayjs from 'dayjs';
 import { CreateGameRequest, GamePlayerInput } from '../types/game';
 
@@ -16,6 +18,24 @@ export function GameForm() {
   const [radiantPlayers, setRadiantPlayers] = useState<GamePlayer[]>([]);
   const [direPlayers, setDirePlayers] = useState<GamePlayer[]>([]);
   const [players, setPlayers] = useState<Player[]>([]);
+  const [loggedIn, setLoggedIn] = useState(auth.getToken() !== null);
+  const handleLogin = useCallback(() => setLoggedIn(true), []);
+
+  // askForToken asks for an API token after a 401, returns whether one was entered.
+  // With Telegram login set up the login button is shown instead.
+  const askForToken = (error: unknown): boolean => {
+    if (!(error instanceof AuthError)) return false;
+    if (error.status === 403) {
+      alert('This API token cannot record games, an admin token is required');
+      return false;
+    }
+    setLoggedIn(false);
+    if (import.meta.env.VITE_TELEGRAM_BOT_USERNAME) return false;
+    const token = prompt('Enter an API token');
+    if (!token) return false;
+    auth.setToken(token);
+    return true;
+  };
 
   useEffect(() => {
     const fetchPlayers = async () => {
@@ -23,11 +43,15 @@ export function GameForm() {
         const fetchedPlayers = await playerService.getPlayers();
         setPlayers(fetchedPlayers.players ?? []);
       } catch (error) {
+        if (askForToken(error)) {
+          fetchPlayers();
+          return;
+        }
         console.error('Error fetching players:', error);
       }
     };
     fetchPlayers();
-  }, []);
+  }, [loggedIn]);
 
   const handlePlayerChange = (team: Team, index: number, playerId: string | null, customNickname?: string) => {
     if (!playerId) return;
@@ -105,11 +129,17 @@ export function GameForm() {
     const gameData: CreateGameRequest = {
       radiant_players: transformPlayers(radiantPlayers),
       dire_players: transformPlayers(direPlayers),
-      winner: winningTeam
+      winner: winningTeam,
+      start_time: timestamp.toISOString()
     };
 
+    const save = async (): Promise<void> => gameService.createGame(gameData).catch(error => {
+      if (askForToken(error)) return save();
+      throw error;
+    });
+
     try {
-      await gameService.createGame(gameData);
+      await save();
 
       // Reset form
       setRadiantPlayers([]);
@@ -126,6 +156,7 @@ export function GameForm() {
       <Paper elevation={1} sx={{ p: 3, borderRadius: 2 }}>
         <Stack spacing={4}>
           <Typography variant="h4">Record New Game</Typography>
+          {!loggedIn && <TelegramLogin onLogin={handleLogin} />}
           
           <LocalizationProvider dateAdapter={AdapterDayjs}>
             <DateTimePicker
@@ -0,0 +1,49 @@
+import { useEffect, useRef } from 'react';
+import { auth, AuthError, TelegramUser } from '../services/auth';
+
+// Username of the bot the Login Widget is set up for, the widget is hidden without it
+const BOT_USERNAME = import.meta.env.VITE_TELEGRAM_BOT_USERNAME as string | undefined;
+
+declare global {
+  interface Window {
+    onTelegramAuth?: (user: TelegramUser) => void;
+  }
+}
+
+export function TelegramLogin({ onLogin }: { onLogin: () => void }) {
+  const container = useRef<HTMLDivElement>(null);
+
+  useEffect(() => {
+    const element = container.current;
+    if (!BOT_USERNAME || !element) return;
+
+    window.onTelegramAuth = async (user: TelegramUser) => {
+      try {
+        await auth.loginWithTelegram(user);
+        onLogin();
+      } catch (error) {
+        if (error instanceof AuthError && error.status === 403) {
+          alert('Only admins can record games');
+          return;
+        }
+        console.error('Error logging in with Telegram:', error);
+      }
+    };
+
+    const script = document.createElement('script');
+    script.src = 'https://telegram.org/js/telegram-widget.js?22';
+    script.async = true;
+    script.setAttribute('data-telegram-login', BOT_USERNAME);
+    script.setAttribute('data-size', 'large');
+    script.setAttribute('data-onauth', 'onTelegramAuth(user)');
+    element.appendChild(script);
+
+    return () => {
+      element.replaceChildren();
+      delete window.onTelegramAuth;
+    };
+  }, [onLogin]);
+
+  if (!BOT_USERNAME) return null;
+  return <div ref={container} />;
+}
@@ -0,0 +1,62 @@
+import { API_BASE_URL } from './gameService';
+
+const TOKEN_KEY = 'ymb-cloz-api-token';
+
+// AuthError is thrown for 401 (missing or invalid token) and 403 (token without admin scope)
+export class AuthError extends Error {
+  constructor(public readonly status: 401 | 403, message: string) {
+    super(message);
+    this.name = 'AuthError';
+  }
+}
+
+// TelegramUser is the object the Telegram Login Widget passes to its onauth callback
+export interface TelegramUser {
+  id: number;
+  first_name: string;
+  last_name?: string;
+  username?: string;
+  photo_url?: string;
+  auth_date: number;
+  hash: string;
+}
+
+export const auth = {
+  getToken: (): string | null => localStorage.getItem(TOKEN_KEY),
+
+  setToken: (token: string) => localStorage.setItem(TOKEN_KEY, token.trim()),
+
+  clearToken: () => localStorage.removeItem(TOKEN_KEY),
+
+  // loginWithTelegram exchanges a Login Widget payload for a session token and keeps it
+  loginWithTelegram: async (user: TelegramUser): Promise<void> => {
+    const response = await fetch(`${API_BASE_URL}/auth/telegram`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(user),
+    });
+    await auth.check(response);
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    const session: { token: string } = await response.json();
+    localStorage.setItem(TOKEN_KEY, session.token);
+  },
+
+  headers: (): Record<string, string> => {
+    const token = localStorage.getItem(TOKEN_KEY);
+    return token ? { Authorization: `Bearer ${token}` } : {};
+  },
+
+  // check throws an AuthError for 401 and 403 responses, forgetting a rejected token
+  check: async (response: Response): Promise<void> => {
+    if (response.status !== 401 && response.status !== 403) {
+      return;
+    }
+    const body = await response.json().catch(() => ({}));
+    if (response.status === 401) {
+      localStorage.removeItem(TOKEN_KEY);
+    }
+    throw new AuthError(response.status, body.error ?? response.statusText);
+  },
+};
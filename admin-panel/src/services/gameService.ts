@@ -1,4 +1,5 @@
 import { CreateGameRequest } from '../types/game';
+import { auth } from './auth';
 
 export const API_BASE_URL = 'https://ymb-cloz-production.up.railway.app/api';
 // export const API_BASE_URL = 'http://localhost:8080/api'
@@ -9,10 +10,12 @@ export const gameService = {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
+                ...auth.headers(),
             },
             body: JSON.stringify(gameData),
         });
 
+        await auth.check(response);
         if (!response.ok) {
             throw new Error(`HTTP error! status: ${response.status}`);
         }
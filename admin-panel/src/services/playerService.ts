@@ -1,11 +1,13 @@
 import { Player } from '../types';
 import { API_BASE_URL } from './gameService';
+import { auth } from './auth';
 
 class PlayerService {
   private readonly baseUrl = API_BASE_URL;
 
   async getPlayers(): Promise<{ players: Player[] }> {
-    const response = await fetch(`${this.baseUrl}/players`);
+    const response = await fetch(`${this.baseUrl}/players`, { headers: auth.headers() });
+    await auth.check(response);
     if (!response.ok) {
       throw new Error('Failed to fetch players');
     }
@@ -2,6 +2,9 @@ export type CreateGameRequest = {
 	radiant_players: GamePlayerInput[];
 	dire_players:    GamePlayerInput[];
 	winner: string;
+	start_time?: string;
+	end_time?: string;
+	duration_minutes?: number;
 }
 
 export type GamePlayerInput = {
@@ -9,4 +12,16 @@ export type GamePlayerInput = {
 	nickname?:  string;
 	role:      string;
 	is_captain: boolean;
+	create_new?: boolean;
+	hero?: string;
+	stats?: PerformanceStats;
+}
+
+export type PerformanceStats = {
+	kills: number;
+	deaths: number;
+	assists: number;
+	last_hits?: number;
+	net_worth?: number;
+	hero_damage?: number;
 }
\ No newline at end of file
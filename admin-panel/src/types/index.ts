@@ -5,6 +5,8 @@ export interface Player {
   id: string;
   nickname: string;
   games?: number;
+  is_active?: boolean;
+  steam_account_id?: number;
 }
 
 export interface GamePlayer {
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"ymb-cloz/internal/migrations"
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+)
+
+// runCommand executes a CLI subcommand such as `ymb-cloz migrate up` instead of starting the server
+func runCommand(db *sql.DB, args []string) error {
+	commands := map[string]func(*sql.DB, []string) error{
+		"ratings": runRatings,
+		"tokens":  runTokens,
+		"backup":  runBackup,
+		"restore": runRestore,
+	}
+
+	if args[0] == "migrate" {
+		return runMigrate(db, args[1:])
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+
+	// These work on the schema, so a fresh database is set up first
+	if err := applyMigrations(db); err != nil {
+		return err
+	}
+	return command(db, args[1:])
+}
+
+// applyMigrations brings the schema up to date unless SKIP_MIGRATIONS is set
+func applyMigrations(db *sql.DB) error {
+	if os.Getenv("SKIP_MIGRATIONS") == "true" {
+		return nil
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("error loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		return fmt.Errorf("error applying migrations: %v", err)
+	}
+	return nil
+}
+
+func runMigrate(db *sql.DB, args []string) error {
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		count, err := migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted %d migration(s)\n", count)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%06d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		return fmt.Errorf("usage: migrate up | down [steps] | status")
+	}
+
+	return nil
+}
+
+func runRatings(db *sql.DB, args []string) error {
+	if len(args) == 0 || args[0] != "recompute" {
+		return fmt.Errorf("usage: ratings recompute")
+	}
+
+	ratingService := service.NewRatingService(store.NewRatingStore(db))
+	if err := ratingService.Recompute(); err != nil {
+		return err
+	}
+
+	fmt.Println("Ratings recomputed")
+	return nil
+}
+
+// runBackup writes the whole database as JSON to a file, or to stdout without one
+func runBackup(db *sql.DB, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: backup [file]")
+	}
+
+	backupService := service.NewBackupService(store.NewBackupStore(db), service.NewRatingService(store.NewRatingStore(db)))
+	backup, err := backupService.Backup()
+	if err != nil {
+		return err
+	}
+	for _, problem := range service.CheckBackup(backup) {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", problem)
+	}
+
+	var out io.Writer = os.Stdout
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(backup); err != nil {
+		return fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Backed up %d players and %d games\n", len(backup.Players), len(backup.Games))
+	return nil
+}
+
+// runRestore migrates an empty database and loads a backup file ("-" reads stdin)
+func runRestore(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: restore <file>")
+	}
+
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var backup store.Backup
+	if err := json.NewDecoder(in).Decode(&backup); err != nil {
+		return fmt.Errorf("failed to read backup: %v", err)
+	}
+
+	backupService := service.NewBackupService(store.NewBackupStore(db), service.NewRatingService(store.NewRatingStore(db)))
+	err := backupService.Restore(&backup)
+	var integrity *service.BackupIntegrityError
+	if errors.As(err, &integrity) {
+		for _, problem := range integrity.Problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		return errors.New("backup is inconsistent, nothing was restored")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %d players and %d games\n", len(backup.Players), len(backup.Games))
+	return nil
+}
+
+func runTokens(db *sql.DB, args []string) error {
+	authService := service.NewAuthService(store.NewTokenStore(db), nil)
+	usage := fmt.Errorf("usage: tokens create <name> <read|admin> | revoke <name> | list")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return usage
+		}
+		plain, token, err := authService.CreateToken(args[1], args[2])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s token %q, it will not be shown again:\n%s\n", token.Scope, token.Name, plain)
+	case "revoke":
+		if len(args) != 2 {
+			return usage
+		}
+		if err := authService.RevokeToken(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked token %q\n", args[1])
+	case "list":
+		tokens, err := authService.ListTokens()
+		if err != nil {
+			return err
+		}
+		for _, t := range tokens {
+			state := "never used"
+			if t.LastUsedAt != nil {
+				state = "last used " + t.LastUsedAt.Format("2006-01-02 15:04:05")
+			}
+			if t.RevokedAt != nil {
+				state = "revoked " + t.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%s\t%s\t%s\n", t.Name, t.Scope, state)
+		}
+	default:
+		return usage
+	}
+
+	return nil
+}
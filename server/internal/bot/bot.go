@@ -1,24 +1,49 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
+	"ymb-cloz/internal/models"
 	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
-	bot           *tgbotapi.BotAPI
-	playerService *service.PlayerService
+	bot            *tgbotapi.BotAPI
+	playerService  *service.PlayerService
+	ratingService  *service.RatingService
+	balanceService *service.BalanceService
+	gameService    service.GameService
+	statsService   *service.StatsService
+	seasonService  *service.SeasonService
+	matchImporter  *service.MatchImporter
+	conversations  *service.ConversationService
+	// Telegram users allowed to record games
+	admins service.AdminList
+
+	// Drafts in progress, only touched from the update loop
+	drafts      map[int]*draft
+	nextDraftID int
 }
 
-func NewBot(bot *tgbotapi.BotAPI, playerService *service.PlayerService) *Bot {
+func NewBot(bot *tgbotapi.BotAPI, playerService *service.PlayerService, ratingService *service.RatingService, balanceService *service.BalanceService, gameService service.GameService, statsService *service.StatsService, seasonService *service.SeasonService, matchImporter *service.MatchImporter, conversations *service.ConversationService, admins service.AdminList) *Bot {
 	return &Bot{
-		bot:           bot,
-		playerService: playerService,
+		bot:            bot,
+		playerService:  playerService,
+		ratingService:  ratingService,
+		balanceService: balanceService,
+		gameService:    gameService,
+		statsService:   statsService,
+		seasonService:  seasonService,
+		matchImporter:  matchImporter,
+		conversations:  conversations,
+		admins:         admins,
+		drafts:         make(map[int]*draft),
 	}
 }
 
@@ -46,25 +71,56 @@ func escapeMarkdown(text string) string {
 	return replacer.Replace(text)
 }
 
+// formatWinRate renders stats as "62.5% (5/8)"
+func formatWinRate(stat store.PlayerStats) string {
+	return fmt.Sprintf("%.1f%% (%d/%d)", stat.WinRate, stat.Wins, stat.Games)
+}
+
+func formatGames(stat store.PlayerStats) string {
+	return fmt.Sprintf("%d games", stat.Games)
+}
+
 func (b *Bot) handleHelp(c *tgbotapi.Update) error {
 	helpText := `🎮 *YMB Cloz Bot* 🎮
 
 Available commands:
 /help \- Show this help message
-/top\_winrate \- Show players sorted by win rate
-/top\_games \- Show players sorted by games played
-/top\_captains \- Show top captains by win rate
-/top\_role \<role\> \- Show top players by role \(carry/mid/offlane/pos4/pos5\)
+/top\_winrate \[season\] \- Show players sorted by win rate
+/top\_games \[season\] \- Show players sorted by games played
+/top\_captains \[season\] \- Show top captains by win rate
+/top\_role \<role\> \[season\] \- Show top players by role \(carry/mid/offlane/pos4/pos5\)
+/top\_rating \[season\] \- Show players sorted by skill rating
+/top\_kda \[role\] \[season\] \- Show players with the best average KDA
+/records \[stat\] \[season\] \- Show single game records \(kills/deaths/assists/last\_hits/net\_worth/hero\_damage\)
+/balance \<nick1\> \.\.\. \<nick10\> \- Split ten players into balanced teams
+/draft \<cap1\> \<cap2\> \<nick3\> \.\.\. \<nick10\> \- Start a captain draft
+/import \<match\_id\> \- Record a finished Dota match \(admins only\)
+/newgame \- Record a game step by step \(admins only\)
+/record \<game\> \- Record a game from text like "R: nick\(c\) carry, \.\.\. \| D: \.\.\. \| win R" \(admins only\)
+/duo \[nick\] \[season\] \- Show best and worst duos, or a player's best and worst partners
+/h2h \<nick1\> \<nick2\> \- Show head\-to\-head record of two players
+/stats \<nick\> \- Show player profile
+/streaks \[nick\|season\] \- Show current win/loss streaks and records
+/hero \<name\> \[season\] \- Show hero record and who plays it
+/heroes \[nick\] \[season\] \- Show most played and best heroes, overall or of a player
+/seasons \- List seasons
 /prokuror \- Show prokuror stats
 
+Leaderboards show the current season by default, pass a season number or "all" for the whole history
+
 Example:
-/top\_role carry \- Show top carry players`
+/top\_role carry 2 \- Show top carry players of season 2`
 
 	return b.sendMessage(c.Message.Chat.ID, helpText)
 }
 
 func (b *Bot) handleTopWinRate(c *tgbotapi.Update) error {
-	stats, err := b.playerService.GetTopByWinRate()
+	filter, season, errText := b.seasonFilter(strings.Fields(c.Message.CommandArguments()))
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	stats, err := b.playerService.GetLeaderboard(service.LeaderboardWinRate, filter)
 	if err != nil {
 		log.Printf("Error getting top win rates: %v", err)
 		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
@@ -74,19 +130,24 @@ func (b *Bot) handleTopWinRate(c *tgbotapi.Update) error {
 		return b.sendMessage(c.Message.Chat.ID, "No statistics available")
 	}
 
-	response := "*Top players by win rate:*\n\n"
+	response := fmt.Sprintf("*Top players by win rate, %s:*\n\n", seasonTitle(season))
 	for i, stat := range stats {
 		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
 			i+1,
 			escapeMarkdown(stat.Nickname),
-			escapeMarkdown(stat.Stats))
+			escapeMarkdown(formatWinRate(stat)))
 	}
 
 	return b.sendMessage(c.Message.Chat.ID, response)
 }
 
 func (b *Bot) handleTopGames(c *tgbotapi.Update) error {
-	stats, err := b.playerService.GetTopByGames()
+	filter, season, errText := b.seasonFilter(strings.Fields(c.Message.CommandArguments()))
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	stats, err := b.playerService.GetLeaderboard(service.LeaderboardGames, filter)
 	if err != nil {
 		log.Printf("Error getting top games: %v", err)
 		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
@@ -96,19 +157,24 @@ func (b *Bot) handleTopGames(c *tgbotapi.Update) error {
 		return b.sendMessage(c.Message.Chat.ID, "No statistics available")
 	}
 
-	response := "*Top players by games played:*\n\n"
+	response := fmt.Sprintf("*Top players by games played, %s:*\n\n", seasonTitle(season))
 	for i, stat := range stats {
 		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
 			i+1,
 			escapeMarkdown(stat.Nickname),
-			escapeMarkdown(stat.Stats))
+			escapeMarkdown(formatGames(stat)))
 	}
 
 	return b.sendMessage(c.Message.Chat.ID, response)
 }
 
 func (b *Bot) handleTopCaptains(c *tgbotapi.Update) error {
-	stats, err := b.playerService.GetTopCaptains()
+	filter, season, errText := b.seasonFilter(strings.Fields(c.Message.CommandArguments()))
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	stats, err := b.playerService.GetLeaderboard(service.LeaderboardCaptains, filter)
 	if err != nil {
 		log.Printf("Error getting top captains: %v", err)
 		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
@@ -118,12 +184,12 @@ func (b *Bot) handleTopCaptains(c *tgbotapi.Update) error {
 		return b.sendMessage(c.Message.Chat.ID, "No captain statistics available")
 	}
 
-	response := "*Top captains by win rate:*\n\n"
+	response := fmt.Sprintf("*Top captains by win rate, %s:*\n\n", seasonTitle(season))
 	for i, stat := range stats {
 		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
 			i+1,
 			escapeMarkdown(stat.Nickname),
-			escapeMarkdown(stat.Stats))
+			escapeMarkdown(formatWinRate(stat)))
 	}
 
 	return b.sendMessage(c.Message.Chat.ID, response)
@@ -136,7 +202,13 @@ func (b *Bot) handleTopRole(c *tgbotapi.Update) error {
 	}
 
 	roleStr := strings.ToLower(args[0])
-	stats, err := b.playerService.GetTopByRole(roleStr)
+	filter, season, errText := b.seasonFilter(args[1:])
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+	filter.Role = roleStr
+
+	stats, err := b.playerService.GetLeaderboard(service.LeaderboardRole, filter)
 	if err != nil {
 		log.Printf("Error getting top by role %s: %v", roleStr, err)
 		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
@@ -146,17 +218,111 @@ func (b *Bot) handleTopRole(c *tgbotapi.Update) error {
 		return b.sendMessage(c.Message.Chat.ID, fmt.Sprintf("No statistics available for role: %s", escapeMarkdown(roleStr)))
 	}
 
-	response := fmt.Sprintf("*Top %s players by win rate:*\n\n", escapeMarkdown(roleStr))
+	response := fmt.Sprintf("*Top %s players by win rate, %s:*\n\n", escapeMarkdown(roleStr), seasonTitle(season))
 	for i, stat := range stats {
 		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
 			i+1,
 			escapeMarkdown(stat.Nickname),
-			escapeMarkdown(stat.Stats))
+			escapeMarkdown(formatWinRate(stat)))
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func (b *Bot) handleTopRating(c *tgbotapi.Update) error {
+	filter, season, errText := b.seasonFilter(strings.Fields(c.Message.CommandArguments()))
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	ratings, err := b.ratingService.GetTopRatings(filter.SeasonID, 1, 20)
+	if err != nil {
+		log.Printf("Error getting top ratings: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	if len(ratings) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, "No ratings available")
+	}
+
+	response := fmt.Sprintf("*Top players by rating, %s:*\n\n", seasonTitle(season))
+	for i, r := range ratings {
+		rating := fmt.Sprintf("%.0f ±%.0f (%d games)", r.Rating, r.Deviation, r.Games)
+		if season != nil {
+			// Deviation is only kept for current ratings
+			rating = fmt.Sprintf("%.0f (%d games)", r.Rating, r.Games)
+		}
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
+			i+1,
+			escapeMarkdown(r.Nickname),
+			escapeMarkdown(rating))
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func (b *Bot) handleBalance(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) != 10 {
+		return b.sendMessage(c.Message.Chat.ID, "Please list exactly 10 players\nExample: /balance nick1 nick2 \\.\\.\\. nick10")
+	}
+
+	req := service.BalanceRequest{}
+	for _, nickname := range args {
+		player, errText := b.resolvePlayer(nickname)
+		if player == nil {
+			return b.sendMessage(c.Message.Chat.ID, errText)
+		}
+		req.PlayerIDs = append(req.PlayerIDs, player.ID)
+	}
+
+	if err := req.Validate(); err != nil {
+		return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(err.Error()))
+	}
+
+	result, err := b.balanceService.Balance(&req)
+	if err != nil {
+		log.Printf("Error balancing teams: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error balancing teams")
+	}
+
+	response := "⚖️ *Balanced teams*\n"
+	for _, team := range []struct {
+		name string
+		team service.BalancedTeam
+	}{
+		{"Radiant", result.Radiant},
+		{"Dire", result.Dire},
+	} {
+		response += fmt.Sprintf("\n*%s* \\(%s\\)\n", team.name, escapeMarkdown(fmt.Sprintf("%.0f", team.team.Strength/5)))
+		for _, p := range team.team.Players {
+			response += escapeMarkdown(fmt.Sprintf("%s — %s", p.Role, p.Nickname)) + "\n"
+		}
 	}
+	response += "\n" + escapeMarkdown(fmt.Sprintf("Radiant win chance: %.0f%%", result.RadiantWinProbability*100))
 
 	return b.sendMessage(c.Message.Chat.ID, response)
 }
 
+// resolvePlayer looks up a nickname and returns a ready-to-send error message when it fails
+func (b *Bot) resolvePlayer(nickname string) (*store.Player, string) {
+	player, err := b.playerService.ResolveNickname(nickname)
+	if err == nil {
+		return player, ""
+	}
+
+	var conflictErr *service.NicknameConflictError
+	if errors.As(err, &conflictErr) {
+		return nil, escapeMarkdown(fmt.Sprintf("Unknown player %s, did you mean %s?", nickname, conflictErr.Conflicts[0].Suggestions[0].Nickname))
+	}
+	if err == service.ErrPlayerNotFound {
+		return nil, escapeMarkdown("Unknown player " + nickname)
+	}
+
+	log.Printf("Error resolving player %s: %v", nickname, err)
+	return nil, "Error fetching statistics"
+}
+
 func (b *Bot) handleProkuror(c *tgbotapi.Update) error {
 	stats, err := b.playerService.GetProkurorStats()
 	if err != nil {
@@ -166,7 +332,7 @@ func (b *Bot) handleProkuror(c *tgbotapi.Update) error {
 
 	response := "🚨 *ВЕРХОВНЫЙ ПРОКУРОР* 🚨\n\n"
 	response += fmt.Sprintf("👮‍♂️ *%s* 👮‍♂️\n", escapeMarkdown(stats.Nickname))
-	response += fmt.Sprintf("🚔 *Статистика:* %s 🚓\n", escapeMarkdown(stats.Stats))
+	response += fmt.Sprintf("🚔 *Статистика:* %s 🚓\n", escapeMarkdown(formatWinRate(stats)))
 	response += "\n🏛️ *Закон и порядок* ⚖️\n"
 	response += "🚨 *Справедливость восторжествует* 🚨"
 
@@ -194,6 +360,67 @@ func (b *Bot) handleHappyBirthday(c *tgbotapi.Update) error {
 	return b.sendMessage(c.Message.Chat.ID, response)
 }
 
+// AnnounceGame returns a listener that posts every recorded game with everyone's rating change
+// and any notable streaks it started or broke
+func (b *Bot) AnnounceGame(chatID int64) service.GameListener {
+	return func(event service.GameCreatedEvent) {
+		text := formatGameResult(event)
+
+		var playerIDs []string
+		for _, team := range [][]models.GamePlayer{event.Game.RadiantTeam, event.Game.DireTeam} {
+			for _, p := range team {
+				playerIDs = append(playerIDs, p.PlayerID)
+			}
+		}
+		events, err := b.statsService.GetStreakEvents(event.Game.ID, playerIDs)
+		if err != nil {
+			log.Printf("Error getting streaks for game %s: %v", event.Game.ID, err)
+		} else if len(events) > 0 {
+			text += "\n" + formatStreakEvents(events)
+		}
+
+		if err := b.sendMessage(chatID, text); err != nil {
+			log.Printf("Error announcing game %s: %v", event.Game.ID, err)
+		}
+	}
+}
+
+func formatGameResult(event service.GameCreatedEvent) string {
+	deltas := make(map[string]service.RatingChange)
+	for _, c := range event.RatingChanges {
+		deltas[c.PlayerID] = c
+	}
+
+	response := "🎮 *Game recorded*\n"
+	for _, team := range []struct {
+		name    string
+		side    string
+		players []models.GamePlayer
+	}{
+		{"Radiant", "RADIANT", event.Game.RadiantTeam},
+		{"Dire", "DIRE", event.Game.DireTeam},
+	} {
+		header := fmt.Sprintf("\n*%s*", team.name)
+		if event.Game.Winner == team.side {
+			header += " 🏆"
+		}
+		response += header + "\n"
+
+		for _, p := range team.players {
+			line := p.Nickname
+			if p.IsCaptain {
+				line += " (c)"
+			}
+			if c, ok := deltas[p.PlayerID]; ok {
+				line += fmt.Sprintf(" — %.0f (%+.0f)", c.After, c.Delta)
+			}
+			response += escapeMarkdown(line) + "\n"
+		}
+	}
+
+	return response
+}
+
 func (b *Bot) sendMessage(chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = tgbotapi.ModeMarkdownV2
@@ -201,6 +428,32 @@ func (b *Bot) sendMessage(chatID int64, text string) error {
 	return err
 }
 
+func (b *Bot) editMessage(chatID int64, messageID int, text string, markup *tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = tgbotapi.ModeMarkdownV2
+	edit.ReplyMarkup = markup
+	_, err := b.bot.Send(edit)
+	return err
+}
+
+func (b *Bot) answerCallback(query *tgbotapi.CallbackQuery, text string) error {
+	_, err := b.bot.Request(tgbotapi.NewCallback(query.ID, text))
+	return err
+}
+
+func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) error {
+	switch {
+	case strings.HasPrefix(query.Data, "draft:"):
+		return b.handleDraftCallback(query)
+	case strings.HasPrefix(query.Data, newGameKind+":"):
+		return b.handleNewGameCallback(query)
+	case strings.HasPrefix(query.Data, recordKind+":"):
+		return b.handleRecordCallback(query)
+	default:
+		return b.answerCallback(query, "Unknown action")
+	}
+}
+
 func (b *Bot) Start() error {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 10
@@ -208,6 +461,13 @@ func (b *Bot) Start() error {
 	updates := b.bot.GetUpdatesChan(u)
 
 	for update := range updates {
+		if update.CallbackQuery != nil {
+			if err := b.handleCallback(update.CallbackQuery); err != nil {
+				log.Printf("Error handling callback: %v", err)
+			}
+			continue
+		}
+
 		if update.Message == nil {
 			continue
 		}
@@ -224,6 +484,36 @@ func (b *Bot) Start() error {
 			err = b.handleTopCaptains(&update)
 		case "top_role":
 			err = b.handleTopRole(&update)
+		case "top_rating":
+			err = b.handleTopRating(&update)
+		case "top_kda":
+			err = b.handleTopKDA(&update)
+		case "records":
+			err = b.handleRecords(&update)
+		case "balance":
+			err = b.handleBalance(&update)
+		case "draft":
+			err = b.handleDraft(&update)
+		case "import":
+			err = b.handleImport(&update)
+		case "newgame":
+			err = b.handleNewGame(&update)
+		case "record":
+			err = b.handleRecord(&update)
+		case "duo":
+			err = b.handleDuo(&update)
+		case "h2h":
+			err = b.handleHeadToHead(&update)
+		case "seasons":
+			err = b.handleSeasons(&update)
+		case "stats":
+			err = b.handleStats(&update)
+		case "streaks":
+			err = b.handleStreaks(&update)
+		case "hero":
+			err = b.handleHero(&update)
+		case "heroes":
+			err = b.handleHeroes(&update)
 		case "prokuror":
 			err = b.handleProkuror(&update)
 			//case "happy_birthday":
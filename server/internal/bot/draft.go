@@ -0,0 +1,305 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"ymb-cloz/internal/models"
+	"ymb-cloz/internal/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Snake pick order for the eight non-captain players: A, B, B, A, A, B, B, A
+var draftOrder = []int{0, 1, 1, 0, 0, 1, 1, 0}
+
+var draftSides = [2]string{"Radiant", "Dire"}
+
+// Telegram usernames can be changed by anyone to match a nickname, so seats are
+// handed out by the draft creator or an admin rather than matched by name
+type draftCaptain struct {
+	player store.Player
+	// Telegram user seated as this captain, zero until seated
+	userID int64
+	// Telegram user asking for the seat, waiting for the creator or an admin
+	requestID   int64
+	requestName string
+}
+
+// telegramName is how a Telegram user is shown in draft messages
+func telegramName(user *tgbotapi.User) string {
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+type draft struct {
+	id        int
+	chatID    int64
+	messageID int
+	creatorID int64
+	captains  [2]draftCaptain
+	pool      []store.Player
+	picked    []bool
+	teams     [2][]store.Player
+	pick      int
+}
+
+func (d *draft) claimed() bool {
+	return d.captains[0].userID != 0 && d.captains[1].userID != 0
+}
+
+func (d *draft) done() bool {
+	return d.pick >= len(draftOrder)
+}
+
+func (d *draft) turn() int {
+	return draftOrder[d.pick]
+}
+
+func (d *draft) text() string {
+	response := "🎯 *Captain draft*\n"
+	for side, captain := range d.captains {
+		response += fmt.Sprintf("\n*%s* \\- %s \\(c\\)", draftSides[side], escapeMarkdown(captain.player.Nickname))
+		for _, p := range d.teams[side] {
+			response += ", " + escapeMarkdown(p.Nickname)
+		}
+	}
+	response += "\n\n"
+
+	switch {
+	case !d.claimed():
+		for _, captain := range d.captains {
+			if captain.userID == 0 && captain.requestID != 0 {
+				response += escapeMarkdown(fmt.Sprintf("%s asks to be %s\n", captain.requestName, captain.player.Nickname))
+			}
+		}
+		response += escapeMarkdown("Captains, press your name to ask for your seat, whoever started the draft or an admin confirms it.")
+	case !d.done():
+		response += fmt.Sprintf("%s to pick", escapeMarkdown(d.captains[d.turn()].player.Nickname))
+	}
+
+	return response
+}
+
+func (d *draft) keyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	if !d.claimed() {
+		var row []tgbotapi.InlineKeyboardButton
+		for side, captain := range d.captains {
+			switch {
+			case captain.userID != 0:
+			case captain.requestID != 0:
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(
+						"✅ Seat "+captain.requestName+" as "+captain.player.Nickname,
+						fmt.Sprintf("draft:%d:seat:%d", d.id, side)),
+					tgbotapi.NewInlineKeyboardButtonData("✖", fmt.Sprintf("draft:%d:reject:%d", d.id, side))))
+			default:
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+					"I'm "+captain.player.Nickname,
+					fmt.Sprintf("draft:%d:claim:%d", d.id, side)))
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	} else {
+		var row []tgbotapi.InlineKeyboardButton
+		for i, p := range d.pool {
+			if d.picked[i] {
+				continue
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.Nickname, fmt.Sprintf("draft:%d:pick:%d", d.id, i)))
+			if len(row) == 2 {
+				rows = append(rows, row)
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", fmt.Sprintf("draft:%d:cancel:0", d.id))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// pendingGame turns a finished draft into rosters waiting for roles and a winner
+func (d *draft) pendingGame() *models.PendingGame {
+	game := &models.PendingGame{Source: "draft"}
+	for side, captain := range d.captains {
+		players := []models.PendingPlayer{{
+			PlayerID:  captain.player.ID,
+			Nickname:  captain.player.Nickname,
+			IsCaptain: true,
+		}}
+		for _, p := range d.teams[side] {
+			players = append(players, models.PendingPlayer{PlayerID: p.ID, Nickname: p.Nickname})
+		}
+		if side == 0 {
+			game.RadiantPlayers = players
+		} else {
+			game.DirePlayers = players
+		}
+	}
+	return game
+}
+
+func (b *Bot) handleDraft(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) != 10 {
+		return b.sendMessage(c.Message.Chat.ID, "Please list the two captains followed by 8 players\nExample: /draft cap1 cap2 nick3 \\.\\.\\. nick10")
+	}
+
+	var players []store.Player
+	seen := make(map[string]bool)
+	for _, nickname := range args {
+		player, errText := b.resolvePlayer(nickname)
+		if player == nil {
+			return b.sendMessage(c.Message.Chat.ID, errText)
+		}
+		if seen[player.ID] {
+			return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(player.Nickname+" is listed twice"))
+		}
+		seen[player.ID] = true
+		players = append(players, *player)
+	}
+
+	b.nextDraftID++
+	d := &draft{
+		id:        b.nextDraftID,
+		chatID:    c.Message.Chat.ID,
+		creatorID: c.Message.From.ID,
+		captains:  [2]draftCaptain{{player: players[0]}, {player: players[1]}},
+		pool:      players[2:],
+		picked:    make([]bool, len(players)-2),
+	}
+
+	msg := tgbotapi.NewMessage(d.chatID, d.text())
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.ReplyMarkup = d.keyboard()
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		return err
+	}
+
+	d.messageID = sent.MessageID
+	b.drafts[d.id] = d
+	return nil
+}
+
+// handleDraftCallback processes "draft:<id>:<action>:<arg>" button presses
+func (b *Bot) handleDraftCallback(query *tgbotapi.CallbackQuery) error {
+	parts := strings.Split(query.Data, ":")
+	if len(parts) != 4 {
+		return b.answerCallback(query, "Unknown action")
+	}
+	id, err1 := strconv.Atoi(parts[1])
+	arg, err2 := strconv.Atoi(parts[3])
+	if err1 != nil || err2 != nil {
+		return b.answerCallback(query, "Unknown action")
+	}
+
+	d, ok := b.drafts[id]
+	if !ok {
+		// Drafts are kept in memory, so a restart also ends them
+		return b.answerCallback(query, "This draft is over or the bot restarted, start a new /draft")
+	}
+	userID := query.From.ID
+	// The draft creator and admins decide who sits where
+	host := userID == d.creatorID || b.admins.Contains(userID)
+
+	switch parts[2] {
+	case "claim":
+		if arg < 0 || arg > 1 || d.captains[arg].userID != 0 {
+			return b.answerCallback(query, "This captain is already taken")
+		}
+		if d.captains[1-arg].userID == userID || d.captains[1-arg].requestID == userID {
+			return b.answerCallback(query, "You are already the other captain")
+		}
+		captain := &d.captains[arg]
+		switch {
+		case host:
+			captain.userID, captain.requestID = userID, 0
+		case captain.requestID != 0:
+			return b.answerCallback(query, "Someone already asked for this seat")
+		default:
+			captain.requestID, captain.requestName = userID, telegramName(query.From)
+		}
+
+	case "seat", "reject":
+		if !host {
+			return b.answerCallback(query, "Only whoever started the draft or an admin can seat captains")
+		}
+		if arg < 0 || arg > 1 || d.captains[arg].requestID == 0 {
+			return b.answerCallback(query, "Nobody is waiting for this seat")
+		}
+		captain := &d.captains[arg]
+		if parts[2] == "seat" {
+			captain.userID = captain.requestID
+		}
+		captain.requestID, captain.requestName = 0, ""
+
+	case "pick":
+		if !d.claimed() {
+			return b.answerCallback(query, "Both captains must take their seats first")
+		}
+		if d.captains[d.turn()].userID != userID {
+			return b.answerCallback(query, "It's not your turn")
+		}
+		if arg < 0 || arg >= len(d.pool) || d.picked[arg] {
+			return b.answerCallback(query, "This player is already picked")
+		}
+		d.picked[arg] = true
+		d.teams[d.turn()] = append(d.teams[d.turn()], d.pool[arg])
+		d.pick++
+
+	case "cancel":
+		if d.creatorID != userID && d.captains[0].userID != userID && d.captains[1].userID != userID {
+			return b.answerCallback(query, "Only captains can cancel the draft")
+		}
+		delete(b.drafts, id)
+		if err := b.editMessage(d.chatID, d.messageID, "Draft cancelled", nil); err != nil {
+			return err
+		}
+		return b.answerCallback(query, "Draft cancelled")
+
+	default:
+		return b.answerCallback(query, "Unknown action")
+	}
+
+	if d.done() {
+		delete(b.drafts, id)
+		return b.finishDraft(query, d)
+	}
+
+	markup := d.keyboard()
+	if err := b.editMessage(d.chatID, d.messageID, d.text(), &markup); err != nil {
+		return err
+	}
+	return b.answerCallback(query, "")
+}
+
+func (b *Bot) finishDraft(query *tgbotapi.CallbackQuery, d *draft) error {
+	game := d.pendingGame()
+	if err := b.gameService.CreatePendingGame(game); err != nil {
+		log.Printf("Error saving drafted game: %v", err)
+		if err := b.editMessage(d.chatID, d.messageID, d.text()+"Error saving the drafted game", nil); err != nil {
+			return err
+		}
+		return b.answerCallback(query, "Error saving the drafted game")
+	}
+
+	text := d.text() + escapeMarkdown(fmt.Sprintf("Draft complete! Pending game %s is ready to be recorded.", game.ID))
+	if err := b.editMessage(d.chatID, d.messageID, text, nil); err != nil {
+		return err
+	}
+	return b.answerCallback(query, "Draft complete")
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ymb-cloz/internal/service"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Minimum games on a hero before it shows up among the best win rates
+const heroMinGames = 3
+
+func (b *Bot) handleHero(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, "Please specify a hero\nExample: /hero Shadow Fiend")
+	}
+
+	// Hero names can have several words, a season may follow them
+	var seasonArgs []string
+	if len(args) > 1 && isSeasonRef(args[len(args)-1]) {
+		seasonArgs = args[len(args)-1:]
+		args = args[:len(args)-1]
+	}
+	name := strings.Join(args, " ")
+
+	filter, season, errText := b.seasonFilter(seasonArgs)
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	details, err := b.statsService.GetHero(name, filter.SeasonID)
+	if err == service.ErrHeroNotFound {
+		return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(fmt.Sprintf("Unknown hero: %s", name)))
+	}
+	if err != nil {
+		log.Printf("Error getting hero stats: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	response := fmt.Sprintf("🦸 *%s, %s*\n\n", escapeMarkdown(details.Hero.LocalizedName), seasonTitle(season))
+	if details.Games == 0 {
+		return b.sendMessage(c.Message.Chat.ID, response+"Nobody has played this hero yet")
+	}
+
+	response += escapeMarkdown(fmt.Sprintf("%.1f%% (%d/%d)", details.WinRate, details.Wins, details.Games)) + "\n\n"
+	response += "*Played by:*\n"
+	for i, p := range details.Players {
+		if i == 10 {
+			break
+		}
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
+			i+1,
+			escapeMarkdown(p.Nickname),
+			escapeMarkdown(fmt.Sprintf("%.1f%% (%d/%d)", p.WinRate, p.Wins, p.Games)))
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func (b *Bot) handleHeroes(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+
+	playerID := ""
+	title := "Heroes"
+	if len(args) > 0 && !isSeasonRef(args[0]) {
+		player, errText := b.resolvePlayer(args[0])
+		if player == nil {
+			return b.sendMessage(c.Message.Chat.ID, errText)
+		}
+		playerID = player.ID
+		title = "Heroes of " + player.Nickname
+		args = args[1:]
+	}
+
+	filter, season, errText := b.seasonFilter(args)
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	report, err := b.statsService.GetHeroes(playerID, filter.SeasonID, heroMinGames, 5)
+	if err != nil {
+		log.Printf("Error getting hero stats: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	if len(report.MostPlayed) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, "No heroes recorded yet")
+	}
+
+	response := fmt.Sprintf("🦸 *%s, %s*\n\n*Most played:*\n", escapeMarkdown(title), seasonTitle(season))
+	response += formatHeroes(report.MostPlayed)
+	if len(report.BestWinRate) > 0 {
+		response += fmt.Sprintf("\n*Best win rate \\(%d\\+ games\\):*\n", heroMinGames)
+		response += formatHeroes(report.BestWinRate)
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func formatHeroes(stats []service.HeroStats) string {
+	response := ""
+	for i, h := range stats {
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
+			i+1,
+			escapeMarkdown(h.Hero),
+			escapeMarkdown(fmt.Sprintf("%.1f%% (%d/%d)", h.WinRate, h.Wins, h.Games)))
+	}
+	return response
+}
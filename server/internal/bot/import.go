@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"ymb-cloz/internal/service"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func (b *Bot) handleImport(c *tgbotapi.Update) error {
+	user := c.Message.From
+	if !b.admins.Contains(user.ID) {
+		return b.sendMessage(c.Message.Chat.ID, "Only admins can import matches")
+	}
+
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) != 1 {
+		return b.sendMessage(c.Message.Chat.ID, "Please specify a match ID\nExample: /import 7712345678")
+	}
+
+	matchID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || matchID <= 0 {
+		return b.sendMessage(c.Message.Chat.ID, "Match ID must be a number")
+	}
+
+	game, err := b.matchImporter.Import(&service.ImportMatchRequest{MatchID: matchID})
+
+	var unmapped *service.UnmappedAccountsError
+	if errors.As(err, &unmapped) {
+		response := "Some players are not linked to a Steam account:\n"
+		for _, a := range unmapped.Accounts {
+			line := a.PersonaName
+			if a.AccountID != nil {
+				line += fmt.Sprintf(" (%d)", *a.AccountID)
+			} else {
+				line += " (anonymous)"
+			}
+			if a.Hero != "" {
+				line += " - " + a.Hero
+			}
+			response += escapeMarkdown(line) + "\n"
+		}
+		response += "\nLink them in the admin panel and try again"
+		return b.sendMessage(c.Message.Chat.ID, response)
+	}
+	if errors.Is(err, service.ErrMatchNotFound) {
+		return b.sendMessage(c.Message.Chat.ID, "Match not found")
+	}
+	if err == service.ErrMatchAlreadyRecorded {
+		return b.sendMessage(c.Message.Chat.ID, "This match has already been recorded")
+	}
+	if errors.Is(err, service.ErrInvalidMatch) {
+		return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(err.Error()))
+	}
+	if err != nil {
+		log.Printf("Error importing match %d: %v", matchID, err)
+		return b.sendMessage(c.Message.Chat.ID, "Error importing match")
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(fmt.Sprintf("Match %d recorded as game %s", matchID, game.ID)))
+}
@@ -0,0 +1,510 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ymb-cloz/internal/models"
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// newGameKind names /newgame conversations in the conversation store
+	newGameKind = "newgame"
+	// Players shown per page while picking a team
+	newGamePageSize = 12
+)
+
+// Steps of the /newgame wizard, players, roles and captain are repeated for both teams
+const (
+	stepPlayers = "players"
+	stepRoles   = "roles"
+	stepCaptain = "captain"
+	stepWinner  = "winner"
+	stepConfirm = "confirm"
+	stepSaved   = "saved"
+)
+
+var newGameRoles = []models.Role{models.Carry, models.Mid, models.Offlane, models.Pos4, models.Pos5}
+
+var newGameSides = [2]string{"RADIANT", "DIRE"}
+
+type wizardPlayer struct {
+	ID        string      `json:"id"`
+	Nickname  string      `json:"nickname"`
+	Role      models.Role `json:"role,omitempty"`
+	IsCaptain bool        `json:"is_captain,omitempty"`
+}
+
+// newGameWizard is the saved state of a /newgame conversation
+type newGameWizard struct {
+	MessageID int               `json:"message_id"`
+	Step      string            `json:"step"`
+	Side      int               `json:"side"`
+	Page      int               `json:"page"`
+	Teams     [2][]wizardPlayer `json:"teams"`
+	Winner    string            `json:"winner,omitempty"`
+}
+
+func (w *newGameWizard) team() []wizardPlayer {
+	return w.Teams[w.Side]
+}
+
+// nextRole is the first role nobody on the current team plays yet
+func (w *newGameWizard) nextRole() models.Role {
+	taken := make(map[models.Role]bool)
+	for _, p := range w.team() {
+		taken[p.Role] = true
+	}
+	for _, role := range newGameRoles {
+		if !taken[role] {
+			return role
+		}
+	}
+	return ""
+}
+
+func (w *newGameWizard) picked(playerID string) (side, index int, ok bool) {
+	for side, team := range w.Teams {
+		for i, p := range team {
+			if p.ID == playerID {
+				return side, i, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// member returns the index of a player in the current team, -1 if they are not in it
+func (w *newGameWizard) member(playerID string) int {
+	for i, p := range w.team() {
+		if p.ID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *newGameWizard) request() *service.CreateGameRequest {
+	req := &service.CreateGameRequest{Winner: w.Winner}
+	for side, team := range w.Teams {
+		for _, p := range team {
+			id := p.ID
+			input := service.GamePlayerInput{ID: &id, Role: string(p.Role), IsCaptain: p.IsCaptain}
+			if side == 0 {
+				req.RadiantPlayers = append(req.RadiantPlayers, input)
+			} else {
+				req.DirePlayers = append(req.DirePlayers, input)
+			}
+		}
+	}
+	return req
+}
+
+func (w *newGameWizard) text() string {
+	response := "🎮 *New game*\n"
+	if w.Step == stepSaved {
+		response = "✅ *Game recorded*\n"
+	}
+	for side, team := range w.Teams {
+		header := fmt.Sprintf("\n*%s*", sideName(newGameSides[side]))
+		if w.Winner == newGameSides[side] {
+			header += " 🏆"
+		}
+		response += header + "\n"
+		if len(team) == 0 {
+			response += "\\-\n"
+		}
+		for _, p := range team {
+			line := p.Nickname
+			if p.Role != "" {
+				line += " - " + string(p.Role)
+			}
+			if p.IsCaptain {
+				line += " (c)"
+			}
+			response += escapeMarkdown(line) + "\n"
+		}
+	}
+	response += "\n"
+
+	side := sideName(newGameSides[w.Side])
+	switch w.Step {
+	case stepPlayers:
+		response += escapeMarkdown(fmt.Sprintf("Pick 5 %s players (%d/5)", side, len(w.team())))
+	case stepRoles:
+		response += escapeMarkdown(fmt.Sprintf("Who plays %s for %s?", w.nextRole(), side))
+	case stepCaptain:
+		response += escapeMarkdown(fmt.Sprintf("Who is the %s captain?", side))
+	case stepWinner:
+		response += "Who won?"
+	case stepConfirm:
+		response += "Save this game?"
+	}
+	return response
+}
+
+func (w *newGameWizard) keyboard(players []store.Player) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	button := func(text, action, arg string) tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardButtonData(text, newGameKind+":"+action+":"+arg)
+	}
+	addGrid := func(buttons []tgbotapi.InlineKeyboardButton, perRow int) {
+		for len(buttons) > 0 {
+			n := min(perRow, len(buttons))
+			rows = append(rows, buttons[:n])
+			buttons = buttons[n:]
+		}
+	}
+
+	switch w.Step {
+	case stepPlayers:
+		// Players of the other team are not offered again
+		var available []store.Player
+		for _, p := range players {
+			if side, _, ok := w.picked(p.ID); !ok || side == w.Side {
+				available = append(available, p)
+			}
+		}
+		pages := max(1, (len(available)+newGamePageSize-1)/newGamePageSize)
+		page := min(w.Page, pages-1)
+
+		var buttons []tgbotapi.InlineKeyboardButton
+		for _, p := range available[page*newGamePageSize : min((page+1)*newGamePageSize, len(available))] {
+			text := p.Nickname
+			if _, _, ok := w.picked(p.ID); ok {
+				text = "✅ " + text
+			}
+			buttons = append(buttons, button(text, "pick", p.ID))
+		}
+		addGrid(buttons, 3)
+
+		var nav []tgbotapi.InlineKeyboardButton
+		if page > 0 {
+			nav = append(nav, button("◀️", "page", strconv.Itoa(page-1)))
+		}
+		if page < pages-1 {
+			nav = append(nav, button("▶️", "page", strconv.Itoa(page+1)))
+		}
+		if len(w.team()) == 5 {
+			nav = append(nav, button("Next ➡️", "next", ""))
+		}
+		if len(nav) > 0 {
+			rows = append(rows, nav)
+		}
+
+	case stepRoles, stepCaptain:
+		action := "role"
+		if w.Step == stepCaptain {
+			action = "captain"
+		}
+		var buttons []tgbotapi.InlineKeyboardButton
+		for _, p := range w.team() {
+			if w.Step == stepRoles && p.Role != "" {
+				continue
+			}
+			buttons = append(buttons, button(p.Nickname, action, p.ID))
+		}
+		addGrid(buttons, 3)
+
+	case stepWinner:
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{
+			button("Radiant", "winner", "RADIANT"),
+			button("Dire", "winner", "DIRE"),
+		})
+
+	case stepConfirm:
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{button("✅ Save", "save", "")})
+	}
+
+	controls := []tgbotapi.InlineKeyboardButton{}
+	if w.Step != stepPlayers || w.Side > 0 {
+		controls = append(controls, button("Back", "back", ""))
+	}
+	controls = append(controls, button("Cancel", "cancel", ""))
+	rows = append(rows, controls)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// apply performs a button press, returning a message for the user when it is not allowed
+func (w *newGameWizard) apply(action, arg string, players []store.Player) string {
+	switch action {
+	case "page":
+		if w.Step != stepPlayers {
+			return "This step is over"
+		}
+		page, err := strconv.Atoi(arg)
+		if err != nil || page < 0 {
+			return "Unknown action"
+		}
+		w.Page = page
+
+	case "pick":
+		if w.Step != stepPlayers {
+			return "This step is over"
+		}
+		if side, i, ok := w.picked(arg); ok {
+			if side != w.Side {
+				return "This player is already in the other team"
+			}
+			w.Teams[side] = append(w.Teams[side][:i], w.Teams[side][i+1:]...)
+			return ""
+		}
+		if len(w.team()) == 5 {
+			return "This team already has 5 players"
+		}
+		for _, p := range players {
+			if p.ID == arg {
+				w.Teams[w.Side] = append(w.Teams[w.Side], wizardPlayer{ID: p.ID, Nickname: p.Nickname})
+				return ""
+			}
+		}
+		return "Unknown player"
+
+	case "next":
+		if w.Step != stepPlayers || len(w.team()) != 5 {
+			return "Pick 5 players first"
+		}
+		w.Step = stepRoles
+
+	case "role":
+		if w.Step != stepRoles {
+			return "This step is over"
+		}
+		i := w.member(arg)
+		if i == -1 {
+			return "This player is not in the team"
+		}
+		if w.team()[i].Role != "" {
+			return "This player already has a role"
+		}
+		w.team()[i].Role = w.nextRole()
+		if w.nextRole() == "" {
+			w.Step = stepCaptain
+		}
+
+	case "captain":
+		if w.Step != stepCaptain {
+			return "This step is over"
+		}
+		i := w.member(arg)
+		if i == -1 {
+			return "This player is not in the team"
+		}
+		w.team()[i].IsCaptain = true
+		if w.Side == 0 {
+			w.Side, w.Page, w.Step = 1, 0, stepPlayers
+		} else {
+			w.Step = stepWinner
+		}
+
+	case "winner":
+		if w.Step != stepWinner || (arg != "RADIANT" && arg != "DIRE") {
+			return "This step is over"
+		}
+		w.Winner = arg
+		w.Step = stepConfirm
+
+	case "back":
+		w.back()
+
+	default:
+		return "Unknown action"
+	}
+	return ""
+}
+
+// back undoes the last choice
+func (w *newGameWizard) back() {
+	clearCaptain := func() {
+		for i := range w.team() {
+			w.team()[i].IsCaptain = false
+		}
+	}
+
+	switch w.Step {
+	case stepPlayers:
+		if w.Side > 0 {
+			w.Side, w.Step = 0, stepCaptain
+			clearCaptain()
+		}
+	case stepRoles:
+		last := -1
+		for i, p := range w.team() {
+			if p.Role != "" && (last == -1 || roleIndex(p.Role) > roleIndex(w.team()[last].Role)) {
+				last = i
+			}
+		}
+		if last == -1 {
+			w.Step = stepPlayers
+		} else {
+			w.team()[last].Role = ""
+		}
+	case stepCaptain:
+		w.Step = stepRoles
+		w.back()
+	case stepWinner:
+		w.Step = stepCaptain
+		clearCaptain()
+	case stepConfirm:
+		w.Step, w.Winner = stepWinner, ""
+	}
+}
+
+func roleIndex(role models.Role) int {
+	for i, r := range newGameRoles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+func sideName(side string) string {
+	if side == "RADIANT" {
+		return "Radiant"
+	}
+	return "Dire"
+}
+
+// wizardPlayers lists active players alphabetically for the team picker
+func (b *Bot) wizardPlayers() ([]store.Player, error) {
+	all, err := b.playerService.GetAllPlayers()
+	if err != nil {
+		return nil, err
+	}
+	var players []store.Player
+	for _, p := range all {
+		if p.IsActive {
+			players = append(players, p)
+		}
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return strings.ToLower(players[i].Nickname) < strings.ToLower(players[j].Nickname)
+	})
+	return players, nil
+}
+
+func (b *Bot) handleNewGame(c *tgbotapi.Update) error {
+	chatID, user := c.Message.Chat.ID, c.Message.From
+	if !b.admins.Contains(user.ID) {
+		return b.sendMessage(chatID, "Only admins can record games")
+	}
+
+	players, err := b.wizardPlayers()
+	if err != nil {
+		log.Printf("Error getting players: %v", err)
+		return b.sendMessage(chatID, "Error fetching players")
+	}
+
+	w := &newGameWizard{Step: stepPlayers}
+	msg := tgbotapi.NewMessage(chatID, w.text())
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.ReplyMarkup = w.keyboard(players)
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		return err
+	}
+
+	// Starting over replaces any unfinished wizard of this user in this chat
+	w.MessageID = sent.MessageID
+	return b.conversations.Save(chatID, user.ID, newGameKind, w)
+}
+
+// handleNewGameCallback processes "newgame:<action>:<arg>" button presses
+func (b *Bot) handleNewGameCallback(query *tgbotapi.CallbackQuery) error {
+	parts := strings.SplitN(query.Data, ":", 3)
+	if len(parts) != 3 || query.Message == nil {
+		return b.answerCallback(query, "Unknown action")
+	}
+	action, arg := parts[1], parts[2]
+	chatID, user := query.Message.Chat.ID, query.From
+
+	if !b.admins.Contains(user.ID) {
+		return b.answerCallback(query, "Only admins can record games")
+	}
+
+	var w newGameWizard
+	found, err := b.conversations.Load(chatID, user.ID, newGameKind, &w)
+	if err != nil {
+		log.Printf("Error loading new game wizard: %v", err)
+		return b.answerCallback(query, "Error loading the game")
+	}
+	if !found || w.MessageID != query.Message.MessageID {
+		return b.answerCallback(query, "This is not your game or it is over")
+	}
+
+	switch action {
+	case "cancel":
+		if err := b.conversations.Delete(chatID, user.ID, newGameKind); err != nil {
+			log.Printf("Error deleting new game wizard: %v", err)
+		}
+		if err := b.editMessage(chatID, w.MessageID, "Game cancelled", nil); err != nil {
+			return err
+		}
+		return b.answerCallback(query, "Game cancelled")
+	case "save":
+		return b.saveNewGame(query, &w)
+	}
+
+	players, err := b.wizardPlayers()
+	if err != nil {
+		log.Printf("Error getting players: %v", err)
+		return b.answerCallback(query, "Error fetching players")
+	}
+
+	if errText := w.apply(action, arg, players); errText != "" {
+		return b.answerCallback(query, errText)
+	}
+	if err := b.conversations.Save(chatID, user.ID, newGameKind, &w); err != nil {
+		log.Printf("Error saving new game wizard: %v", err)
+		return b.answerCallback(query, "Error saving progress")
+	}
+
+	markup := w.keyboard(players)
+	if err := b.editMessage(chatID, w.MessageID, w.text(), &markup); err != nil {
+		return err
+	}
+	return b.answerCallback(query, "")
+}
+
+func (b *Bot) saveNewGame(query *tgbotapi.CallbackQuery, w *newGameWizard) error {
+	chatID, userID := query.Message.Chat.ID, query.From.ID
+	if w.Step != stepConfirm {
+		return b.answerCallback(query, "The game is not complete yet")
+	}
+
+	// Same checks as the admin panel gets from the API
+	req := w.request()
+	if err := req.Validate(); err != nil {
+		return b.answerCallback(query, err.Error())
+	}
+
+	game, err := b.gameService.CreateGame(req)
+	if errors.Is(err, service.ErrPlayerListedTwice) {
+		return b.answerCallback(query, err.Error())
+	}
+	if err != nil {
+		log.Printf("Error recording game from the bot: %v", err)
+		return b.answerCallback(query, "Error recording the game")
+	}
+
+	if err := b.conversations.Delete(chatID, userID, newGameKind); err != nil {
+		log.Printf("Error deleting new game wizard: %v", err)
+	}
+
+	w.Step = stepSaved
+	text := w.text() + escapeMarkdown("Game "+game.ID)
+	if err := b.editMessage(chatID, w.MessageID, text, nil); err != nil {
+		return err
+	}
+	return b.answerCallback(query, "Game recorded")
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ymb-cloz/internal/service"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Minimum games with recorded stats before a player shows up in /top_kda
+const kdaMinGames = 3
+
+var recordTitles = map[string]string{
+	"kills":       "Most kills",
+	"deaths":      "Most deaths",
+	"assists":     "Most assists",
+	"last_hits":   "Most last hits",
+	"net_worth":   "Highest net worth",
+	"hero_damage": "Most hero damage",
+}
+
+func (b *Bot) handleTopKDA(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+
+	role := ""
+	if len(args) > 0 && !isSeasonRef(args[0]) {
+		role = strings.ToLower(args[0])
+		if !isRole(role) {
+			return b.sendMessage(c.Message.Chat.ID, "Please specify a role: carry/mid/offlane/pos4/pos5\nExample: /top\\_kda carry")
+		}
+		args = args[1:]
+	}
+
+	filter, season, errText := b.seasonFilter(args)
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	stats, err := b.statsService.GetKDALeaderboard(role, filter.SeasonID, kdaMinGames, 10)
+	if err != nil {
+		log.Printf("Error getting KDA leaderboard: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	if len(stats) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(fmt.Sprintf("No players with at least %d games with recorded stats", kdaMinGames)))
+	}
+
+	title := "Best KDA"
+	if role != "" {
+		title += " on " + role
+	}
+	response := fmt.Sprintf("*%s, %s:*\n\n", escapeMarkdown(title), seasonTitle(season))
+	for i, st := range stats {
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
+			i+1,
+			escapeMarkdown(st.Nickname),
+			escapeMarkdown(fmt.Sprintf("%.2f (%.1f/%.1f/%.1f, %d games)", st.KDA, st.AvgKills, st.AvgDeaths, st.AvgAssists, st.Games)))
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func (b *Bot) handleRecords(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+
+	stat := ""
+	if len(args) > 0 && !isSeasonRef(args[0]) {
+		stat = strings.ToLower(args[0])
+		args = args[1:]
+	}
+
+	filter, season, errText := b.seasonFilter(args)
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	if stat == "" {
+		records, err := b.statsService.GetTopRecords(filter.SeasonID)
+		if err != nil {
+			log.Printf("Error getting records: %v", err)
+			return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+		}
+		if len(records) == 0 {
+			return b.sendMessage(c.Message.Chat.ID, "No game stats recorded yet")
+		}
+
+		response := fmt.Sprintf("🏆 *Single game records, %s:*\n\n", seasonTitle(season))
+		for _, r := range records {
+			response += fmt.Sprintf("*%s:* %s\n", escapeMarkdown(recordTitles[r.Stat]), escapeMarkdown(formatRecord(r)))
+		}
+		return b.sendMessage(c.Message.Chat.ID, response)
+	}
+
+	records, err := b.statsService.GetRecords(stat, filter.SeasonID, 10)
+	if err == service.ErrUnknownStat {
+		return b.sendMessage(c.Message.Chat.ID, "Please specify a stat: kills/deaths/assists/last\\_hits/net\\_worth/hero\\_damage\nExample: /records deaths")
+	}
+	if err != nil {
+		log.Printf("Error getting records for %s: %v", stat, err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+	if len(records) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, "No game stats recorded yet")
+	}
+
+	response := fmt.Sprintf("🏆 *%s in a game, %s:*\n\n", escapeMarkdown(recordTitles[stat]), seasonTitle(season))
+	for i, r := range records {
+		response += fmt.Sprintf("%d\\. %s\n", i+1, escapeMarkdown(formatRecord(r)))
+	}
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func isRole(role string) bool {
+	for _, r := range service.Roles {
+		if string(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRecord renders a record as "nick (Hero) - 25, 02.01.2006"
+func formatRecord(r service.GameRecord) string {
+	name := r.Nickname
+	if r.Hero != "" {
+		name += " (" + r.Hero + ")"
+	}
+	return fmt.Sprintf("%s - %d, %s", name, r.Value, r.Timestamp.Format("02.01.2006"))
+}
@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"ymb-cloz/internal/service"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// recordKind names /record conversations in the conversation store
+const recordKind = "record"
+
+// At most this many notation errors are listed in a reply
+const maxNotationErrors = 10
+
+const recordUsage = `Paste the game after the command:
+/record R: nick(c) carry, nick mid, nick offlane, nick pos4, nick pos5
+D: nick 1, nick 2, nick(c) 3, nick 4, nick 5 | win R
+
+Roles can be positions 1-5, a hero may follow the role, (new) marks a new player`
+
+// recordState is the saved state of a /record conversation waiting for confirmation
+type recordState struct {
+	MessageID int                       `json:"message_id"`
+	Request   service.CreateGameRequest `json:"request"`
+}
+
+// escapeCode escapes text for a MarkdownV2 code block
+func escapeCode(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
+
+// formatNotationErrors points at each error under its line of the notation
+func formatNotationErrors(text string, errs service.NotationErrors) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	for i, e := range errs {
+		if i == maxNotationErrors {
+			out = append(out, fmt.Sprintf("... and %d more", len(errs)-i))
+			break
+		}
+		out = append(out, e.String())
+		if e.Line <= len(lines) {
+			line := []rune(strings.TrimSuffix(lines[e.Line-1], "\r"))
+			out = append(out, "  "+string(line), "  "+strings.Repeat(" ", min(e.Column-1, len(line)))+"^")
+		}
+	}
+	return "```\n" + escapeCode(strings.Join(out, "\n")) + "\n```"
+}
+
+func formatGamePreview(preview *service.GamePreview, title string) string {
+	response := title + "\n"
+	for _, side := range newGameSides {
+		header := fmt.Sprintf("\n*%s*", sideName(side))
+		if preview.Request.Winner == side {
+			header += " 🏆"
+		}
+		response += header + "\n"
+
+		for _, p := range preview.Players {
+			if p.Team != side {
+				continue
+			}
+			line := p.Nickname
+			if p.IsCaptain {
+				line += " (c)"
+			}
+			line += " - " + p.Role
+			if p.Hero != "" {
+				line += ", " + p.Hero
+			}
+			switch p.Status {
+			case service.PreviewKnown:
+				if p.Player != p.Nickname {
+					line += " → " + p.Player
+				}
+			case service.PreviewNew:
+				line += " 🆕 new player"
+			case service.PreviewConflict:
+				line += fmt.Sprintf(" ❓ did you mean %s?", p.Suggestions[0].Nickname)
+			}
+			response += escapeMarkdown(line) + "\n"
+		}
+	}
+	return response
+}
+
+func (b *Bot) handleRecord(c *tgbotapi.Update) error {
+	chatID, user := c.Message.Chat.ID, c.Message.From
+	if !b.admins.Contains(user.ID) {
+		return b.sendMessage(chatID, "Only admins can record games")
+	}
+
+	text := c.Message.CommandArguments()
+	if strings.TrimSpace(text) == "" {
+		return b.sendMessage(chatID, escapeMarkdown(recordUsage))
+	}
+
+	req, err := service.ParseGameNotation(text)
+	var notationErrs service.NotationErrors
+	if errors.As(err, &notationErrs) {
+		return b.sendMessage(chatID, "Could not read the game:\n"+formatNotationErrors(text, notationErrs))
+	}
+	if err != nil {
+		log.Printf("Error parsing game notation: %v", err)
+		return b.sendMessage(chatID, "Error reading the game")
+	}
+
+	preview, err := b.playerService.PreviewGame(req)
+	if err != nil {
+		log.Printf("Error previewing game: %v", err)
+		return b.sendMessage(chatID, "Error fetching players")
+	}
+
+	if !preview.Ready {
+		response := formatGamePreview(preview, "🎮 *New game*") + "\n" +
+			escapeMarkdown("Fix the nicknames marked with ❓ or add (new) after them, then send /record again")
+		return b.sendMessage(chatID, response)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatGamePreview(preview, "🎮 *New game*")+"\nSave this game?")
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Save", recordKind+":save"),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", recordKind+":cancel"),
+	))
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		return err
+	}
+
+	// A new /record replaces the previous unconfirmed one of this user in this chat
+	return b.conversations.Save(chatID, user.ID, recordKind, &recordState{MessageID: sent.MessageID, Request: *req})
+}
+
+// handleRecordCallback processes "record:save" and "record:cancel"
+func (b *Bot) handleRecordCallback(query *tgbotapi.CallbackQuery) error {
+	if query.Message == nil {
+		return b.answerCallback(query, "Unknown action")
+	}
+	action := strings.TrimPrefix(query.Data, recordKind+":")
+	chatID, user := query.Message.Chat.ID, query.From
+
+	if !b.admins.Contains(user.ID) {
+		return b.answerCallback(query, "Only admins can record games")
+	}
+
+	var state recordState
+	found, err := b.conversations.Load(chatID, user.ID, recordKind, &state)
+	if err != nil {
+		log.Printf("Error loading recorded game: %v", err)
+		return b.answerCallback(query, "Error loading the game")
+	}
+	if !found || state.MessageID != query.Message.MessageID {
+		return b.answerCallback(query, "This is not your game or it is over")
+	}
+
+	switch action {
+	case "cancel":
+		if err := b.conversations.Delete(chatID, user.ID, recordKind); err != nil {
+			log.Printf("Error deleting recorded game: %v", err)
+		}
+		if err := b.editMessage(chatID, state.MessageID, "Game cancelled", nil); err != nil {
+			return err
+		}
+		return b.answerCallback(query, "Game cancelled")
+	case "save":
+		return b.saveRecordedGame(query, &state)
+	default:
+		return b.answerCallback(query, "Unknown action")
+	}
+}
+
+func (b *Bot) saveRecordedGame(query *tgbotapi.CallbackQuery, state *recordState) error {
+	chatID, userID := query.Message.Chat.ID, query.From.ID
+	req := &state.Request
+
+	game, err := b.gameService.CreateGame(req)
+	var conflictErr *service.NicknameConflictError
+	if errors.As(err, &conflictErr) {
+		// Someone added a similar player since the preview was shown
+		return b.answerCallback(query, "Unknown player "+conflictErr.Conflicts[0].Nickname+", send /record again")
+	}
+	if errors.Is(err, service.ErrPlayerListedTwice) {
+		return b.answerCallback(query, err.Error())
+	}
+	if err != nil {
+		log.Printf("Error recording game from the bot: %v", err)
+		return b.answerCallback(query, "Error recording the game")
+	}
+
+	if err := b.conversations.Delete(chatID, userID, recordKind); err != nil {
+		log.Printf("Error deleting recorded game: %v", err)
+	}
+
+	// The saved game shows who the nicknames were recorded as
+	text := "✅ *Game recorded*\n" + escapeMarkdown("Game "+game.ID)
+	if preview, err := b.playerService.PreviewGame(req); err == nil {
+		text = formatGamePreview(preview, "✅ *Game recorded*") + "\n" + escapeMarkdown("Game "+game.ID)
+	}
+	if err := b.editMessage(chatID, state.MessageID, text, nil); err != nil {
+		return err
+	}
+	return b.answerCallback(query, "Game recorded")
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// seasonFilter builds a leaderboard filter from an optional season argument,
+// defaulting to the current season. The returned season is nil for "all".
+func (b *Bot) seasonFilter(args []string) (store.LeaderboardFilter, *store.Season, string) {
+	ref := ""
+	if len(args) > 0 {
+		ref = args[0]
+	}
+
+	season, err := b.seasonService.ResolveSeason(ref)
+	if err == service.ErrSeasonNotFound {
+		return store.LeaderboardFilter{}, nil, escapeMarkdown(fmt.Sprintf("Season %s not found, see /seasons", ref))
+	}
+	if err != nil {
+		log.Printf("Error resolving season %q: %v", ref, err)
+		return store.LeaderboardFilter{}, nil, "Error fetching statistics"
+	}
+
+	var filter store.LeaderboardFilter
+	if season != nil {
+		filter.SeasonID = season.ID
+	}
+	return filter, season, ""
+}
+
+// isSeasonRef tells a season argument (a number, current or all) apart from a
+// nickname, role or stat in commands where the season is optional
+func isSeasonRef(arg string) bool {
+	if _, err := strconv.Atoi(arg); err == nil {
+		return true
+	}
+	arg = strings.ToLower(arg)
+	return arg == "current" || arg == service.SeasonAll
+}
+
+// seasonTitle is the escaped season name for leaderboard headers
+func seasonTitle(season *store.Season) string {
+	if season == nil {
+		return "all time"
+	}
+	return escapeMarkdown(season.Name)
+}
+
+func (b *Bot) handleSeasons(c *tgbotapi.Update) error {
+	seasons, err := b.seasonService.ListSeasons()
+	if err != nil {
+		log.Printf("Error listing seasons: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching seasons")
+	}
+
+	if len(seasons) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, "No seasons yet")
+	}
+
+	response := "📅 *Seasons:*\n\n"
+	for _, s := range seasons {
+		period := s.StartedAt.Format("02.01.2006") + " - "
+		if s.EndedAt != nil {
+			period += s.EndedAt.Format("02.01.2006")
+		} else {
+			period += "now"
+		}
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n", s.Number, escapeMarkdown(s.Name), escapeMarkdown(period))
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
@@ -0,0 +1,279 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Minimum games together before a pair shows up in /duo
+const duoMinGames = 3
+
+func (b *Bot) handleDuo(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+
+	playerID := ""
+	title := "duos"
+	if len(args) > 0 && !isSeasonRef(args[0]) {
+		player, errText := b.resolvePlayer(args[0])
+		if player == nil {
+			return b.sendMessage(c.Message.Chat.ID, errText)
+		}
+		playerID = player.ID
+		title = fmt.Sprintf("partners of %s", player.Nickname)
+		args = args[1:]
+	}
+
+	filter, season, errText := b.seasonFilter(args)
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	report, err := b.statsService.GetDuos(playerID, filter.SeasonID, duoMinGames, 5)
+	if err != nil {
+		log.Printf("Error getting duo stats: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	if len(report.Best) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(fmt.Sprintf("No duos with at least %d games together", duoMinGames)))
+	}
+
+	response := fmt.Sprintf("🤝 *Best %s, %s:*\n", escapeMarkdown(title), seasonTitle(season))
+	response += formatDuos(report.Best, playerID != "")
+	response += fmt.Sprintf("\n💔 *Worst %s:*\n", escapeMarkdown(title))
+	response += formatDuos(report.Worst, playerID != "")
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+// formatDuos lists pairs, or only the partner when all rows belong to the same player
+func formatDuos(duos []store.DuoStats, partnerOnly bool) string {
+	response := ""
+	for i, duo := range duos {
+		name := duo.Nickname + " + " + duo.PartnerNickname
+		if partnerOnly {
+			name = duo.PartnerNickname
+		}
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n",
+			i+1,
+			escapeMarkdown(name),
+			escapeMarkdown(fmt.Sprintf("%.1f%% (%d/%d)", duo.WinRate, duo.Wins, duo.Games)))
+	}
+	return response
+}
+
+func (b *Bot) handleHeadToHead(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) != 2 {
+		return b.sendMessage(c.Message.Chat.ID, "Please specify two players\nExample: /h2h nick1 nick2")
+	}
+
+	playerA, errText := b.resolvePlayer(args[0])
+	if playerA == nil {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+	playerB, errText := b.resolvePlayer(args[1])
+	if playerB == nil {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+	if playerA.ID == playerB.ID {
+		return b.sendMessage(c.Message.Chat.ID, "Please specify two different players")
+	}
+
+	h2h, err := b.statsService.GetHeadToHead(playerA.ID, playerB.ID)
+	if err != nil {
+		log.Printf("Error getting head-to-head stats: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	response := fmt.Sprintf("⚔️ *%s vs %s*\n\n", escapeMarkdown(h2h.PlayerANick), escapeMarkdown(h2h.PlayerBNick))
+	response += escapeMarkdown(fmt.Sprintf("Against each other: %d games, %d - %d", h2h.GamesAgainst, h2h.WinsA, h2h.WinsB)) + "\n"
+	response += escapeMarkdown(fmt.Sprintf("Together: %d games, %d wins", h2h.GamesTogether, h2h.WinsTogether))
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func (b *Bot) handleStats(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) != 1 {
+		return b.sendMessage(c.Message.Chat.ID, "Please specify a player\nExample: /stats nick")
+	}
+
+	player, errText := b.resolvePlayer(args[0])
+	if player == nil {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	profile, err := b.statsService.GetProfile(player)
+	if err != nil {
+		log.Printf("Error getting player profile: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	rating, err := b.ratingService.GetRating(player.ID)
+	if err != nil {
+		log.Printf("Error getting player rating: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	rivals, err := b.statsService.GetRivals(player.ID)
+	if err != nil {
+		log.Printf("Error getting rivals: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+
+	response := fmt.Sprintf("👤 *%s*\n\n", escapeMarkdown(player.Nickname))
+	response += fmt.Sprintf("*Record:* %s\n", escapeMarkdown(formatWinRecord(profile.Overall)))
+	if rating != nil {
+		response += fmt.Sprintf("*Rating:* %s\n", escapeMarkdown(fmt.Sprintf("%.0f ±%.0f", rating.Rating, rating.Deviation)))
+	}
+	if profile.Overall.Games == 0 {
+		return b.sendMessage(c.Message.Chat.ID, response)
+	}
+
+	response += fmt.Sprintf("*Captain:* %s\n", escapeMarkdown(formatWinRecord(profile.Captain)))
+	response += fmt.Sprintf("*Radiant:* %s\n", escapeMarkdown(formatWinRecord(profile.Radiant)))
+	response += fmt.Sprintf("*Dire:* %s\n", escapeMarkdown(formatWinRecord(profile.Dire)))
+
+	response += "\n*Roles:*\n"
+	for _, role := range profile.Roles {
+		if role.Games > 0 {
+			response += escapeMarkdown(fmt.Sprintf("%s - %s", role.Role, formatWinRecord(role.WinRecord))) + "\n"
+		}
+	}
+
+	form := make([]string, len(profile.Form))
+	for i, g := range profile.Form {
+		form[i] = "L"
+		if g.IsWinner {
+			form[i] = "W"
+		}
+	}
+	response += fmt.Sprintf("\n*Form:* %s\n", escapeMarkdown(strings.Join(form, " ")+" (newest first)"))
+
+	if profile.BestPartner != nil {
+		partner := profile.BestPartner
+		response += fmt.Sprintf("*Best partner:* %s\n", escapeMarkdown(fmt.Sprintf("%s (%d - %d)", partner.PartnerNickname, partner.Wins, partner.Losses)))
+	}
+	if profile.MostFrequentOpponent != nil {
+		response += fmt.Sprintf("*Most faced:* %s\n", escapeMarkdown(formatOpponent(profile.MostFrequentOpponent)))
+	}
+	if rivals.Nemesis != nil {
+		response += fmt.Sprintf("*Nemesis:* %s\n", escapeMarkdown(formatOpponent(rivals.Nemesis)))
+	}
+	if rivals.FavouriteVictim != nil {
+		response += fmt.Sprintf("*Favourite victim:* %s\n", escapeMarkdown(formatOpponent(rivals.FavouriteVictim)))
+	}
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func formatWinRecord(r service.WinRecord) string {
+	return fmt.Sprintf("%.1f%% (%d/%d)", r.WinRate, r.Wins, r.Games)
+}
+
+func formatOpponent(o *store.OpponentStats) string {
+	return fmt.Sprintf("%s (%d - %d)", o.Nickname, o.Wins, o.Losses)
+}
+
+func (b *Bot) handleStreaks(c *tgbotapi.Update) error {
+	args := strings.Fields(c.Message.CommandArguments())
+
+	if len(args) > 0 && !isSeasonRef(args[0]) {
+		player, errText := b.resolvePlayer(args[0])
+		if player == nil {
+			return b.sendMessage(c.Message.Chat.ID, errText)
+		}
+
+		streaks, err := b.statsService.GetStreaks("", player.ID)
+		if err != nil {
+			log.Printf("Error getting streaks: %v", err)
+			return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+		}
+		if len(streaks) == 0 {
+			return b.sendMessage(c.Message.Chat.ID, escapeMarkdown(player.Nickname+" has not played any games yet"))
+		}
+
+		s := streaks[0]
+		response := fmt.Sprintf("🔥 *Streaks of %s*\n\n", escapeMarkdown(player.Nickname))
+		response += fmt.Sprintf("*Current:* %s\n", escapeMarkdown(formatStreak(s.CurrentType, s.Current)))
+		response += fmt.Sprintf("*Longest win streak:* %d\n", s.LongestWin)
+		response += fmt.Sprintf("*Longest loss streak:* %d\n", s.LongestLoss)
+		return b.sendMessage(c.Message.Chat.ID, response)
+	}
+
+	filter, season, errText := b.seasonFilter(args)
+	if errText != "" {
+		return b.sendMessage(c.Message.Chat.ID, errText)
+	}
+
+	streaks, err := b.statsService.GetStreaks(filter.SeasonID)
+	if err != nil {
+		log.Printf("Error getting streaks: %v", err)
+		return b.sendMessage(c.Message.Chat.ID, "Error fetching statistics")
+	}
+	if len(streaks) == 0 {
+		return b.sendMessage(c.Message.Chat.ID, "No games recorded yet")
+	}
+
+	response := fmt.Sprintf("🔥 *Current streaks, %s:*\n", seasonTitle(season))
+	count := 0
+	for _, s := range streaks {
+		if s.Current < 2 || count == 10 {
+			continue
+		}
+		count++
+		response += fmt.Sprintf("%d\\. *%s* \\- %s\n", count, escapeMarkdown(s.Nickname), escapeMarkdown(formatStreak(s.CurrentType, s.Current)))
+	}
+	if count == 0 {
+		response += "Nobody is on a streak\n"
+	}
+
+	bestWin, bestLoss := streaks[0], streaks[0]
+	for _, s := range streaks {
+		if s.LongestWin > bestWin.LongestWin {
+			bestWin = s
+		}
+		if s.LongestLoss > bestLoss.LongestLoss {
+			bestLoss = s
+		}
+	}
+	response += "\n🏅 *Records:*\n"
+	response += escapeMarkdown(fmt.Sprintf("Longest win streak: %s, %d", bestWin.Nickname, bestWin.LongestWin)) + "\n"
+	response += escapeMarkdown(fmt.Sprintf("Longest loss streak: %s, %d", bestLoss.Nickname, bestLoss.LongestLoss)) + "\n"
+
+	return b.sendMessage(c.Message.Chat.ID, response)
+}
+
+func formatStreak(streakType string, length int) string {
+	if streakType == "win" {
+		return fmt.Sprintf("%d wins in a row", length)
+	}
+	return fmt.Sprintf("%d losses in a row", length)
+}
+
+// formatStreakEvents describes notable streaks started or broken by a game
+func formatStreakEvents(events []service.StreakEvent) string {
+	response := ""
+	for _, e := range events {
+		var line string
+		switch {
+		case e.Started && e.Type == "win":
+			line = fmt.Sprintf("🔥 %s is on a %d game win streak", e.Nickname, e.Length)
+		case e.Started:
+			line = fmt.Sprintf("🥶 %s has lost %d games in a row", e.Nickname, e.Length)
+		case e.Type == "win":
+			line = fmt.Sprintf("🛑 %s's %d game win streak is over", e.Nickname, e.Length)
+		default:
+			line = fmt.Sprintf("🎉 %s broke a %d game losing streak", e.Nickname, e.Length)
+		}
+		response += escapeMarkdown(line) + "\n"
+	}
+	return response
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"ymb-cloz/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey stores the authenticated *service.Principal in the gin context
+const principalKey = "principal"
+
+// RequireScope rejects requests without a bearer token allowing scope: 401 when
+// the token is missing or invalid, 403 when it lacks the scope
+func RequireScope(auth *service.AuthService, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="ymb-cloz"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		principal, err := auth.Authenticate(strings.TrimSpace(token))
+		if err == service.ErrInvalidToken {
+			c.Header("WWW-Authenticate", `Bearer realm="ymb-cloz", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+			return
+		}
+
+		if !principal.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": scope + " scope required"})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
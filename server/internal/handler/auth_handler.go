@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"ymb-cloz/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	telegram *service.TelegramAuth
+}
+
+func NewAuthHandler(telegram *service.TelegramAuth) *AuthHandler {
+	return &AuthHandler{telegram: telegram}
+}
+
+// TelegramLogin exchanges the object passed to the Login Widget's onauth
+// callback for a session token
+func (h *AuthHandler) TelegramLogin(c *gin.Context) {
+	// Numbers are kept as written, the signature covers their exact text
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	fields := make(map[string]string, len(payload))
+	for key, value := range payload {
+		switch v := value.(type) {
+		case string:
+			fields[key] = v
+		case json.Number:
+			fields[key] = v.String()
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid value of " + key})
+			return
+		}
+	}
+
+	session, err := h.telegram.Login(fields)
+	if err == service.ErrTelegramLoginDisabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTelegramLogin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrNotAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
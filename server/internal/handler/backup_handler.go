@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupHandler struct {
+	service *service.BackupService
+}
+
+func NewBackupHandler(service *service.BackupService) *BackupHandler {
+	return &BackupHandler{service: service}
+}
+
+func (h *BackupHandler) Backup(c *gin.Context) {
+	backup, err := h.service.Backup()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+
+	filename := fmt.Sprintf("ymb-cloz-%s.json", backup.CreatedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.JSON(http.StatusOK, backup)
+}
+
+func (h *BackupHandler) Restore(c *gin.Context) {
+	var backup store.Backup
+	if err := c.ShouldBindJSON(&backup); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.Restore(&backup)
+
+	var integrity *service.BackupIntegrityError
+	if errors.As(err, &integrity) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "backup is inconsistent", "problems": integrity.Problems})
+		return
+	}
+	if errors.Is(err, service.ErrUnsupportedBackup) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err == store.ErrDatabaseNotEmpty {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore backup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "backup restored successfully",
+		"created_at": backup.CreatedAt.Format(time.RFC3339),
+		"players":    len(backup.Players),
+		"games":      len(backup.Games),
+	})
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"ymb-cloz/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BalanceHandler struct {
+	service *service.BalanceService
+}
+
+func NewBalanceHandler(service *service.BalanceService) *BalanceHandler {
+	return &BalanceHandler{service: service}
+}
+
+func (h *BalanceHandler) Balance(c *gin.Context) {
+	var req service.BalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range req.PlayerIDs {
+		if !isValidUUID(id) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID: " + id})
+			return
+		}
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Balance(&req)
+	if errors.Is(err, service.ErrPlayerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to balance teams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": result})
+}
@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,85 +28,301 @@ func (h *GameHandler) CreateGame(c *gin.Context) {
 	}
 
 	// Validate request
-	if len(req.RadiantPlayers) != 5 || len(req.DirePlayers) != 5 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "each team must have exactly 5 players"})
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PendingGameID != nil && !isValidUUID(*req.PendingGameID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pending game ID"})
+		return
+	}
+
+	game, err := h.service.CreateGame(&req)
+	if writeNicknameConflict(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrPlayerListedTwice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrPendingGameNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrMatchAlreadyRecorded {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "game created successfully", "game": game})
+}
+
+func (h *GameHandler) ListGames(c *gin.Context) {
+	var filter store.GameFilter
+	var err error
+
+	filter.Limit, err = queryInt(c, "limit", 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Limit == 0 || filter.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	filter.Offset, err = queryInt(c, "offset", 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter.PlayerID = c.Query("player")
+	if filter.PlayerID != "" && !isValidUUID(filter.PlayerID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	filter.Role = c.Query("role")
+	if filter.Role != "" && !isValidRole(filter.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + filter.Role})
+		return
+	}
+
+	filter.IsCaptain, err = queryBool(c, "captain")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Role and captain describe what the filtered player did in the game
+	if (filter.Role != "" || filter.IsCaptain) && filter.PlayerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role and captain filters require a player"})
 		return
 	}
 
-	if req.Winner != "RADIANT" && req.Winner != "DIRE" {
+	filter.Winner = c.Query("winner")
+	if filter.Winner != "" && filter.Winner != "RADIANT" && filter.Winner != "DIRE" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "winner must be either RADIANT or DIRE"})
 		return
 	}
 
-	// Validate roles and captains
-	radiantCaptains := 0
-	direCaptains := 0
-	roles := make(map[string]bool)
+	filter.SeasonID = c.Query("season_id")
+	if filter.SeasonID != "" && !isValidUUID(filter.SeasonID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season ID"})
+		return
+	}
 
-	// Check Radiant team
-	for _, p := range req.RadiantPlayers {
-		if p.ID != nil && p.Nickname != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "player ID and nickname cannot both be provided"})
-			return
-		}
-		if p.ID == nil && p.Nickname == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "player ID or nickname must be provided"})
-			return
-		}
-		if p.Role != "carry" && p.Role != "mid" && p.Role != "offlane" && p.Role != "pos4" && p.Role != "pos5" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + p.Role})
-			return
-		}
-		if p.IsCaptain {
-			radiantCaptains++
-		}
-		roles[p.Role] = true
+	filter.From, err = queryTime(c, "from", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.To, err = queryTime(c, "to", true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	games, err := h.service.ListGames(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch games"})
+		return
+	}
+
+	c.JSON(http.StatusOK, games)
+}
+
+func (h *GameHandler) GetGame(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game ID"})
+		return
 	}
-	if radiantCaptains != 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Radiant team must have exactly one captain"})
+
+	game, err := h.service.GetGame(id)
+	if err == service.ErrGameNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	if len(roles) != 5 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Radiant team must have all unique roles"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch game"})
 		return
 	}
 
-	// Reset roles map for Dire team
-	roles = make(map[string]bool)
+	c.JSON(http.StatusOK, game)
+}
+
+func (h *GameHandler) UpdateGame(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game ID"})
+		return
+	}
 
-	// Check Dire team
-	for _, p := range req.DirePlayers {
-		if p.ID != nil && p.Nickname != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "player ID and nickname cannot both be provided"})
-			return
-		}
-		if p.ID == nil && p.Nickname == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "player ID or nickname must be provided"})
+	var req service.CreateGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.UpdateGame(id, &req)
+	if err == service.ErrGameNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if writeNicknameConflict(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrPlayerListedTwice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "game updated successfully"})
+}
+
+func (h *GameHandler) DeleteGame(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game ID"})
+		return
+	}
+
+	err := h.service.DeleteGame(id)
+	if err == service.ErrGameNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "game deleted successfully"})
+}
+
+func (h *GameHandler) ListPendingGames(c *gin.Context) {
+	games, err := h.service.ListPendingGames()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending games"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pending_games": games})
+}
+
+func (h *GameHandler) DeletePendingGame(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pending game ID"})
+		return
+	}
+
+	err := h.service.DeletePendingGame(id)
+	if err == service.ErrPendingGameNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "pending game deleted successfully"})
+}
+
+// writeNicknameConflict responds with 409 and "did you mean" suggestions when
+// the request contains nicknames that look like existing players
+func writeNicknameConflict(c *gin.Context, err error) bool {
+	var conflictErr *service.NicknameConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":     err.Error(),
+		"conflicts": conflictErr.Conflicts,
+	})
+	return true
+}
+
+// Largest CSV file accepted by ImportGamesCSV
+const maxCSVSize = 10 << 20
+
+// ImportGamesCSV takes the file either as a multipart "file" field or as the raw body
+func (h *GameHandler) ImportGamesCSV(c *gin.Context) {
+	dryRun, err := queryBool(c, "dry_run")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCSVSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
 			return
 		}
-		if p.Role != "carry" && p.Role != "mid" && p.Role != "offlane" && p.Role != "pos4" && p.Role != "pos5" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + p.Role})
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if p.IsCaptain {
-			direCaptains++
-		}
-		roles[p.Role] = true
+		defer f.Close()
+		body = f
 	}
-	if direCaptains != 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Dire team must have exactly one captain"})
+
+	result, err := h.service.ImportGamesCSV(body, dryRun)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large"})
 		return
 	}
-	if len(roles) != 5 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Dire team must have all unique roles"})
+	if errors.Is(err, service.ErrInvalidCSV) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	err := h.service.CreateGame(&req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import games"})
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *GameHandler) ExportGamesCSV(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := h.service.ExportGamesCSV(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export games"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "game created successfully"})
+	c.Header("Content-Disposition", `attachment; filename="games.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
 }
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImportHandler struct {
+	importer *service.MatchImporter
+}
+
+func NewImportHandler(importer *service.MatchImporter) *ImportHandler {
+	return &ImportHandler{importer: importer}
+}
+
+func (h *ImportHandler) ImportMatch(c *gin.Context) {
+	var req service.ImportMatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, playerID := range req.Accounts {
+		if !isValidUUID(playerID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID: " + playerID})
+			return
+		}
+	}
+
+	game, err := h.importer.Import(&req)
+
+	var unmapped *service.UnmappedAccountsError
+	if errors.As(err, &unmapped) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "accounts": unmapped.Accounts})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidAccounts) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidMatch) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMatchNotFound) || errors.Is(err, service.ErrPlayerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrMatchAlreadyRecorded || err == store.ErrDuplicateSteamAccount {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMatchUnavailable) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import match"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "match imported successfully", "game": game})
+}
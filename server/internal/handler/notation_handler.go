@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"ymb-cloz/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotationHandler struct {
+	players *service.PlayerService
+}
+
+func NewNotationHandler(players *service.PlayerService) *NotationHandler {
+	return &NotationHandler{players: players}
+}
+
+type parseGameRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// ParseGame turns compact game notation into a request for POST /games and
+// previews who will be recorded, nothing is saved
+func (h *NotationHandler) ParseGame(c *gin.Context) {
+	var body parseGameRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req, err := service.ParseGameNotation(body.Text)
+	var notationErrs service.NotationErrors
+	if errors.As(err, &notationErrs) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid game notation", "errors": notationErrs})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := h.players.PreviewGame(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
@@ -1,18 +1,22 @@
 package handler
 
 import (
+	"math"
 	"net/http"
+	"strings"
 	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PlayerHandler struct {
 	service *service.PlayerService
+	seasons *service.SeasonService
 }
 
-func NewPlayerHandler(service *service.PlayerService) *PlayerHandler {
-	return &PlayerHandler{service: service}
+func NewPlayerHandler(service *service.PlayerService, seasons *service.SeasonService) *PlayerHandler {
+	return &PlayerHandler{service: service, seasons: seasons}
 }
 
 func (h *PlayerHandler) GetAllPlayers(c *gin.Context) {
@@ -25,44 +29,239 @@ func (h *PlayerHandler) GetAllPlayers(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"players": players})
 }
 
-func (h *PlayerHandler) GetTopByWinRate(c *gin.Context) {
-	stats, err := h.service.GetTopByWinRate()
+func (h *PlayerHandler) GetLeaderboard(c *gin.Context) {
+	kind := c.Param("kind")
+
+	var filter store.LeaderboardFilter
+	var err error
+
+	filter.Limit, err = queryInt(c, "limit", 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Limit == 0 || filter.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	filter.MinGames, err = queryInt(c, "min_games", 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter.Role = c.Query("role")
+	if filter.Role != "" && !isValidRole(filter.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + filter.Role})
+		return
+	}
+
+	filter.CaptainOnly, err = queryBool(c, "captain")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter.Side = c.Query("side")
+	if filter.Side != "" && filter.Side != "RADIANT" && filter.Side != "DIRE" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be either RADIANT or DIRE"})
+		return
+	}
+
+	filter.From, err = queryTime(c, "from", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.To, err = queryTime(c, "to", true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	season, ok := resolveSeason(c, h.seasons, c.Query("season"))
+	if !ok {
+		return
+	}
+	if season != nil {
+		filter.SeasonID = season.ID
+	}
+
+	stats, err := h.service.GetLeaderboard(kind, filter)
+	if err == service.ErrUnknownLeaderboard {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrRoleRequired {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats, "season": season})
+}
+
+func (h *PlayerHandler) UpdatePlayer(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	var req service.UpdatePlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Nickname == nil && req.IsActive == nil && req.SteamAccountID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nickname, is_active or steam_account_id must be provided"})
+		return
+	}
+	if req.SteamAccountID != nil && (*req.SteamAccountID < 0 || *req.SteamAccountID > math.MaxUint32) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "steam_account_id must be a 32-bit Steam account ID"})
+		return
+	}
+	if req.Nickname != nil {
+		nickname := strings.TrimSpace(*req.Nickname)
+		if nickname == "" || len(nickname) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nickname must be between 1 and 255 characters"})
+			return
+		}
+	}
+
+	player, err := h.service.UpdatePlayer(id, &req)
+	if err == service.ErrPlayerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == store.ErrDuplicateNickname || err == store.ErrDuplicateSteamAccount {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update player"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"player": player})
+}
+
+func (h *PlayerHandler) MergePlayers(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	var req service.MergePlayersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidUUID(req.SourceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid source player ID"})
+		return
+	}
+	if req.SourceID == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot merge a player into itself"})
+		return
+	}
+
+	player, err := h.service.MergePlayers(id, req.SourceID)
+	if err == service.ErrPlayerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrMergeConflict {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch win rate statistics"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge players"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"stats": stats})
+
+	c.JSON(http.StatusOK, gin.H{"player": player})
 }
 
-func (h *PlayerHandler) GetTopByGames(c *gin.Context) {
-	stats, err := h.service.GetTopByGames()
+func (h *PlayerHandler) GetAliases(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	aliases, err := h.service.GetAliases(id)
+	if err == service.ErrPlayerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch games statistics"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch aliases"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"stats": stats})
+
+	c.JSON(http.StatusOK, gin.H{"aliases": aliases})
 }
 
-func (h *PlayerHandler) GetTopCaptains(c *gin.Context) {
-	stats, err := h.service.GetTopCaptains()
+func (h *PlayerHandler) AddAlias(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	var req service.AliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias := strings.TrimSpace(req.Alias)
+	if alias == "" || len(alias) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "alias must be between 1 and 255 characters"})
+		return
+	}
+
+	err := h.service.AddAlias(id, alias)
+	if err == service.ErrPlayerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == store.ErrDuplicateNickname {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch captain statistics"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add alias"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"stats": stats})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "alias added successfully"})
 }
 
-func (h *PlayerHandler) GetTopByRole(c *gin.Context) {
-	role := c.Param("role")
-	if role == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Role parameter is required"})
+func (h *PlayerHandler) DeleteAlias(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
 		return
 	}
 
-	stats, err := h.service.GetTopByRole(role)
+	deleted, err := h.service.DeleteAlias(id, c.Param("alias"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role statistics"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alias"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"stats": stats})
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "alias not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "alias deleted successfully"})
 }
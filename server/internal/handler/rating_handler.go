@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"ymb-cloz/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RatingHandler struct {
+	service *service.RatingService
+	seasons *service.SeasonService
+}
+
+func NewRatingHandler(service *service.RatingService, seasons *service.SeasonService) *RatingHandler {
+	return &RatingHandler{service: service, seasons: seasons}
+}
+
+func (h *RatingHandler) GetRatings(c *gin.Context) {
+	limit, err := queryInt(c, "limit", 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if limit == 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	minGames, err := queryInt(c, "min_games", 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	ratings, err := h.service.GetTopRatings(seasonID, minGames, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ratings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ratings": ratings})
+}
+
+func (h *RatingHandler) Recompute(c *gin.Context) {
+	if err := h.service.Recompute(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ratings recomputed successfully"})
+}
+
+func (h *RatingHandler) GetRatingHistory(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	history, err := h.service.GetRatingHistory(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rating history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"ymb-cloz/internal/service"
+	"ymb-cloz/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SeasonHandler struct {
+	service *service.SeasonService
+}
+
+func NewSeasonHandler(service *service.SeasonService) *SeasonHandler {
+	return &SeasonHandler{service: service}
+}
+
+func (h *SeasonHandler) ListSeasons(c *gin.Context) {
+	seasons, err := h.service.ListSeasons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+}
+
+func (h *SeasonHandler) GetStandings(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season ID"})
+		return
+	}
+
+	season, standings, err := h.service.GetStandings(id)
+	if err == service.ErrSeasonNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == service.ErrSeasonOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch standings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"season": season, "standings": standings})
+}
+
+func (h *SeasonHandler) CloseSeason(c *gin.Context) {
+	var req service.CloseSeasonRequest
+	// The body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.service.CloseSeason(&req)
+	if err == service.ErrSeasonNotFound {
+		c.JSON(http.StatusConflict, gin.H{"error": "no season is in progress"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close season"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Season closed successfully", "season": result})
+}
+
+// resolveSeason reads a season query value (empty/current, all, a number or an ID)
+// and writes the error response itself when it cannot be resolved
+func resolveSeason(c *gin.Context, seasons *service.SeasonService, ref string) (*store.Season, bool) {
+	if _, err := strconv.Atoi(ref); err != nil && ref != "" && ref != "current" && ref != service.SeasonAll && !isValidUUID(ref) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "season must be current, all, a season number or a season ID"})
+		return nil, false
+	}
+
+	season, err := seasons.ResolveSeason(ref)
+	if err == service.ErrSeasonNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch season"})
+		return nil, false
+	}
+	return season, true
+}
+
+// querySeasonID resolves the season query parameter, defaulting to the current
+// season, and returns its ID or an empty string for all time
+func querySeasonID(c *gin.Context, seasons *service.SeasonService) (string, bool) {
+	season, ok := resolveSeason(c, seasons, c.Query("season"))
+	if !ok || season == nil {
+		return "", ok
+	}
+	return season.ID, true
+}
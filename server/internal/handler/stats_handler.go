@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"net/http"
+	"ymb-cloz/internal/heroes"
+	"ymb-cloz/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatsHandler struct {
+	service *service.StatsService
+	players *service.PlayerService
+	seasons *service.SeasonService
+}
+
+func NewStatsHandler(service *service.StatsService, players *service.PlayerService, seasons *service.SeasonService) *StatsHandler {
+	return &StatsHandler{service: service, players: players, seasons: seasons}
+}
+
+func (h *StatsHandler) GetDuos(c *gin.Context) {
+	limit, err := queryInt(c, "limit", 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if limit == 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	minGames, err := queryInt(c, "min_games", 3)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	playerID := c.Query("player")
+	if playerID != "" && !isValidUUID(playerID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	duos, err := h.service.GetDuos(playerID, seasonID, minGames, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duo statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, duos)
+}
+
+func (h *StatsHandler) GetHeadToHead(c *gin.Context) {
+	playerA, playerB := c.Query("a"), c.Query("b")
+	if !isValidUUID(playerA) || !isValidUUID(playerB) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a and b must be valid player IDs"})
+		return
+	}
+	if playerA == playerB {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a and b must be different players"})
+		return
+	}
+
+	h2h, err := h.service.GetHeadToHead(playerA, playerB)
+	if err == service.ErrPlayerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch head-to-head statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"h2h": h2h})
+}
+
+func (h *StatsHandler) GetRivals(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	rivals, err := h.service.GetRivals(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rivals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rivals": rivals})
+}
+
+func (h *StatsHandler) GetProfile(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	player, err := h.players.GetPlayer(id)
+	if err == service.ErrPlayerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.service.GetProfile(player)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"profile": profile})
+}
+
+func (h *StatsHandler) GetStreaks(c *gin.Context) {
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	streaks, err := h.service.GetStreaks(seasonID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch streaks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"streaks": streaks})
+}
+
+func (h *StatsHandler) GetPlayerStreaks(c *gin.Context) {
+	id := c.Param("id")
+	if !isValidUUID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	streaks, err := h.service.GetStreaks("", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch streaks"})
+		return
+	}
+
+	streak := service.Streaks{PlayerID: id}
+	if len(streaks) > 0 {
+		streak = streaks[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"streaks": streak})
+}
+
+func (h *StatsHandler) GetDurations(c *gin.Context) {
+	split, err := queryInt(c, "split_minutes", service.DefaultShortGameMinutes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if split == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "split_minutes must be positive"})
+		return
+	}
+
+	playerID := c.Query("player")
+	if playerID != "" && !isValidUUID(playerID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetDurationStats(playerID, seasonID, split)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duration statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *StatsHandler) ListHeroes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"heroes": heroes.All()})
+}
+
+func (h *StatsHandler) GetHero(c *gin.Context) {
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	details, err := h.service.GetHero(c.Param("hero"), seasonID)
+	if err == service.ErrHeroNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hero statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+func (h *StatsHandler) GetHeroStats(c *gin.Context) {
+	playerID := c.Param("id")
+	if playerID == "" {
+		playerID = c.Query("player")
+	}
+	if playerID != "" && !isValidUUID(playerID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	limit, err := queryInt(c, "limit", 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if limit == 0 || limit > 150 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 150"})
+		return
+	}
+
+	minGames, err := queryInt(c, "min_games", 3)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetHeroes(playerID, seasonID, minGames, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hero statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *StatsHandler) GetKDALeaderboard(c *gin.Context) {
+	limit, err := queryInt(c, "limit", 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if limit == 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	minGames, err := queryInt(c, "min_games", 3)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := c.Query("role")
+	if role != "" && !isValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + role})
+		return
+	}
+
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetKDALeaderboard(role, seasonID, minGames, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch KDA leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+func (h *StatsHandler) GetRecords(c *gin.Context) {
+	limit, err := queryInt(c, "limit", 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if limit == 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	seasonID, ok := querySeasonID(c, h.seasons)
+	if !ok {
+		return
+	}
+
+	records, err := h.service.GetRecords(c.Param("stat"), seasonID, limit)
+	if err == service.ErrUnknownStat {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"records": records})
+}
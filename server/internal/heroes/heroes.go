@@ -0,0 +1,78 @@
+// Package heroes holds the embedded Dota 2 hero catalog. Hero IDs match the
+// ones used by the Dota 2 API and OpenDota.
+package heroes
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed heroes.json
+var catalogJSON []byte
+
+type Hero struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	LocalizedName string   `json:"localized_name"`
+	Aliases       []string `json:"aliases,omitempty"`
+}
+
+var (
+	catalog []Hero
+	byID    = make(map[int]Hero)
+	byKey   = make(map[string]Hero)
+)
+
+func init() {
+	if err := json.Unmarshal(catalogJSON, &catalog); err != nil {
+		panic("invalid hero catalog: " + err.Error())
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].LocalizedName < catalog[j].LocalizedName
+	})
+
+	for _, h := range catalog {
+		byID[h.ID] = h
+		byKey[key(h.Name)] = h
+		byKey[key(h.LocalizedName)] = h
+		for _, alias := range h.Aliases {
+			byKey[key(alias)] = h
+		}
+	}
+}
+
+// key normalizes a hero name so that "Nature's Prophet", "natures prophet"
+// and "natures_prophet" all look the same
+func key(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// All returns every hero sorted by localized name
+func All() []Hero {
+	return catalog
+}
+
+func ByID(id int) (Hero, bool) {
+	h, ok := byID[id]
+	return h, ok
+}
+
+// Lookup finds a hero by localized name, internal name or common abbreviation
+func Lookup(name string) (Hero, bool) {
+	h, ok := byKey[key(name)]
+	return h, ok
+}
+
+// LocalizedName returns the display name of a hero ID, or an empty string if it is unknown
+func LocalizedName(id int) string {
+	return byID[id].LocalizedName
+}
@@ -1,3 +1,3 @@
 DROP TABLE IF EXISTS game_players;
 DROP TABLE IF EXISTS games;
-DROP TABLE IF EXISTS players; 
\ No newline at end of file
+DROP TABLE IF EXISTS players; 
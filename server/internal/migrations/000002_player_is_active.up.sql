@@ -0,0 +1,2 @@
+-- Inactive players keep their history but are hidden from leaderboards
+ALTER TABLE players ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,9 @@
+-- Alternative spellings that resolve to an existing player
+CREATE TABLE IF NOT EXISTS player_aliases (
+    alias VARCHAR(255) NOT NULL,
+    player_id UUID NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS player_aliases_alias_lower_idx ON player_aliases (LOWER(alias));
+CREATE INDEX IF NOT EXISTS player_aliases_player_id_idx ON player_aliases (player_id);
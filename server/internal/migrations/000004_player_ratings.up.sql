@@ -0,0 +1,9 @@
+-- Glicko-2 skill rating per player, rebuilt from game_players on demand
+CREATE TABLE IF NOT EXISTS player_ratings (
+    player_id UUID PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+    rating DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    games INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
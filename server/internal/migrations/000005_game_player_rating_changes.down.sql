@@ -0,0 +1,2 @@
+ALTER TABLE game_players DROP COLUMN IF EXISTS rating_after;
+ALTER TABLE game_players DROP COLUMN IF EXISTS rating_before;
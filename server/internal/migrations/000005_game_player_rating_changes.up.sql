@@ -0,0 +1,3 @@
+-- Rating of each player right before and after the game
+ALTER TABLE game_players ADD COLUMN IF NOT EXISTS rating_before DOUBLE PRECISION;
+ALTER TABLE game_players ADD COLUMN IF NOT EXISTS rating_after DOUBLE PRECISION;
@@ -0,0 +1,8 @@
+-- Rosters agreed on (e.g. by a captain draft) whose result has not been recorded yet
+CREATE TABLE IF NOT EXISTS pending_games (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    radiant_players JSONB NOT NULL,
+    dire_players JSONB NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'draft',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS season_standings;
+DROP INDEX IF EXISTS idx_games_season_id;
+ALTER TABLE games DROP COLUMN IF EXISTS season_id;
+DROP TABLE IF EXISTS seasons;
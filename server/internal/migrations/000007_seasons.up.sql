@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS seasons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    number INTEGER NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one season can be open at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_seasons_active ON seasons ((ended_at IS NULL)) WHERE ended_at IS NULL;
+
+-- Existing history becomes the first season
+INSERT INTO seasons (number, name, started_at)
+SELECT 1, 'Season 1', COALESCE(MIN(timestamp), CURRENT_TIMESTAMP) FROM games;
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS season_id UUID REFERENCES seasons(id);
+UPDATE games SET season_id = (SELECT id FROM seasons WHERE number = 1);
+CREATE INDEX IF NOT EXISTS idx_games_season_id ON games(season_id);
+
+-- Final standings archived when a season is closed
+CREATE TABLE IF NOT EXISTS season_standings (
+    season_id UUID NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    player_id UUID NOT NULL,
+    nickname VARCHAR(255) NOT NULL,
+    games INTEGER NOT NULL,
+    wins INTEGER NOT NULL,
+    losses INTEGER NOT NULL,
+    win_rate DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (season_id, player_id)
+);
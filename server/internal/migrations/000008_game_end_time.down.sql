@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_games_timestamp;
+ALTER TABLE games DROP CONSTRAINT IF EXISTS games_end_after_start;
+ALTER TABLE games DROP COLUMN IF EXISTS end_time;
@@ -0,0 +1,4 @@
+-- games.timestamp is the start of the game, end_time is only known when it was recorded
+ALTER TABLE games ADD COLUMN IF NOT EXISTS end_time TIMESTAMP WITH TIME ZONE;
+ALTER TABLE games ADD CONSTRAINT games_end_after_start CHECK (end_time IS NULL OR end_time > timestamp);
+CREATE INDEX IF NOT EXISTS idx_games_timestamp ON games(timestamp);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_game_players_hero_id;
+ALTER TABLE game_players DROP COLUMN IF EXISTS hero_id;
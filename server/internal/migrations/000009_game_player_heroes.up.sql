@@ -0,0 +1,3 @@
+-- Hero IDs from the embedded hero catalog, NULL when the hero was not recorded
+ALTER TABLE game_players ADD COLUMN IF NOT EXISTS hero_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_game_players_hero_id ON game_players(hero_id);
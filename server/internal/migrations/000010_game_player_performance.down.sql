@@ -0,0 +1,7 @@
+ALTER TABLE game_players
+    DROP COLUMN IF EXISTS kills,
+    DROP COLUMN IF EXISTS deaths,
+    DROP COLUMN IF EXISTS assists,
+    DROP COLUMN IF EXISTS last_hits,
+    DROP COLUMN IF EXISTS net_worth,
+    DROP COLUMN IF EXISTS hero_damage;
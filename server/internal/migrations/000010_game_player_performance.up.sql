@@ -0,0 +1,8 @@
+-- Optional per-player performance numbers, NULL when they were not recorded
+ALTER TABLE game_players
+    ADD COLUMN IF NOT EXISTS kills INTEGER CHECK (kills >= 0),
+    ADD COLUMN IF NOT EXISTS deaths INTEGER CHECK (deaths >= 0),
+    ADD COLUMN IF NOT EXISTS assists INTEGER CHECK (assists >= 0),
+    ADD COLUMN IF NOT EXISTS last_hits INTEGER CHECK (last_hits >= 0),
+    ADD COLUMN IF NOT EXISTS net_worth INTEGER CHECK (net_worth >= 0),
+    ADD COLUMN IF NOT EXISTS hero_damage INTEGER CHECK (hero_damage >= 0);
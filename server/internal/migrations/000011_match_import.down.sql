@@ -0,0 +1,2 @@
+ALTER TABLE games DROP COLUMN IF EXISTS match_id;
+ALTER TABLE players DROP COLUMN IF EXISTS steam_account_id;
@@ -0,0 +1,5 @@
+-- 32-bit Steam account IDs as used by the Dota 2 API, to map imported matches to players
+ALTER TABLE players ADD COLUMN IF NOT EXISTS steam_account_id BIGINT UNIQUE;
+
+-- Dota match ID of imported games, so the same match is never recorded twice
+ALTER TABLE games ADD COLUMN IF NOT EXISTS match_id BIGINT UNIQUE;
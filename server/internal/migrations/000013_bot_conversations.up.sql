@@ -0,0 +1,9 @@
+-- State of multi-step bot conversations such as /newgame, so a restart does not lose them
+CREATE TABLE IF NOT EXISTS bot_conversations (
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    state JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, user_id, kind)
+);
@@ -13,10 +13,30 @@ type Player struct {
 type GamePlayer struct {
 	GameID    string `json:"game_id"`
 	PlayerID  string `json:"player_id"`
+	Nickname  string `json:"nickname"`
 	Team      string `json:"team"`
 	Role      Role   `json:"role"`
 	IsCaptain bool   `json:"is_captain"`
 	IsWinner  bool   `json:"is_winner"`
+	HeroID    *int   `json:"hero_id,omitempty"`
+	Hero      string `json:"hero,omitempty"`
+
+	Stats *PerformanceStats `json:"stats,omitempty"`
+
+	RatingBefore *float64 `json:"rating_before,omitempty"`
+	RatingAfter  *float64 `json:"rating_after,omitempty"`
+	RatingDelta  *float64 `json:"rating_delta,omitempty"`
+}
+
+// PerformanceStats are end-of-game numbers of one player, K/D/A is always
+// present when stats were recorded
+type PerformanceStats struct {
+	Kills      int  `json:"kills"`
+	Deaths     int  `json:"deaths"`
+	Assists    int  `json:"assists"`
+	LastHits   *int `json:"last_hits,omitempty"`
+	NetWorth   *int `json:"net_worth,omitempty"`
+	HeroDamage *int `json:"hero_damage,omitempty"`
 }
 
 type Role string
@@ -32,8 +52,25 @@ const (
 type Game struct {
 	ID          string       `json:"id"`
 	StartTime   time.Time    `json:"start_time"`
-	EndTime     time.Time    `json:"end_time"`
+	EndTime     *time.Time   `json:"end_time,omitempty"`
 	RadiantTeam []GamePlayer `json:"radiant_team"`
 	DireTeam    []GamePlayer `json:"dire_team"`
 	Winner      string       `json:"winner"`
+	SeasonID    *string      `json:"season_id,omitempty"`
+	MatchID     *int64       `json:"match_id,omitempty"`
+}
+
+type PendingPlayer struct {
+	PlayerID  string `json:"player_id"`
+	Nickname  string `json:"nickname"`
+	IsCaptain bool   `json:"is_captain"`
+}
+
+// PendingGame is a pair of rosters waiting for roles and a winner to be recorded
+type PendingGame struct {
+	ID             string          `json:"id"`
+	RadiantPlayers []PendingPlayer `json:"radiant_players"`
+	DirePlayers    []PendingPlayer `json:"dire_players"`
+	Source         string          `json:"source"`
+	CreatedAt      time.Time       `json:"created_at"`
 }
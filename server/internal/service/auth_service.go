@@ -0,0 +1,123 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"ymb-cloz/internal/store"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid or revoked token")
+	ErrInvalidScope  = errors.New("scope must be either read or admin")
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// tokenPrefix makes tokens easy to recognize in config files and secret scanners
+const tokenPrefix = "ymb_"
+
+// Principal is whoever made an authenticated request
+type Principal struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// Allows reports whether the principal may use routes requiring scope, admin includes read
+func (p *Principal) Allows(scope string) bool {
+	return p.Scope == ScopeAdmin || p.Scope == scope
+}
+
+type AuthService struct {
+	tokens *store.TokenStore
+	// telegram verifies Telegram login sessions, nil when they are not accepted
+	telegram *TelegramAuth
+}
+
+func NewAuthService(tokens *store.TokenStore, telegram *TelegramAuth) *AuthService {
+	return &AuthService{tokens: tokens, telegram: telegram}
+}
+
+// CreateToken generates a new token, the plain value is only ever returned here
+func (s *AuthService) CreateToken(name, scope string) (string, *store.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("token name cannot be empty")
+	}
+	if scope != ScopeRead && scope != ScopeAdmin {
+		return "", nil, ErrInvalidScope
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %v", err)
+	}
+	plain := tokenPrefix + hex.EncodeToString(secret)
+
+	token, err := s.tokens.CreateToken(name, hashToken(plain), scope)
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+func (s *AuthService) ListTokens() ([]store.APIToken, error) {
+	return s.tokens.ListTokens()
+}
+
+func (s *AuthService) RevokeToken(name string) error {
+	found, err := s.tokens.RevokeToken(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a bearer token, an API token or a Telegram login
+// session, to the principal it was issued to
+func (s *AuthService) Authenticate(plain string) (*Principal, error) {
+	if !strings.HasPrefix(plain, tokenPrefix) {
+		if s.telegram == nil {
+			return nil, ErrInvalidToken
+		}
+		principal, err := s.telegram.VerifySession(plain)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		return principal, nil
+	}
+
+	token, err := s.tokens.GetActiveTokenByHash(hashToken(plain))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up token: %v", err)
+	}
+
+	if err := s.tokens.TouchToken(token.ID); err != nil {
+		log.Printf("Error updating token usage: %v", err)
+	}
+
+	return &Principal{Name: token.Name, Scope: token.Scope}, nil
+}
+
+// Tokens are long random strings, so a plain SHA-256 is enough to keep them
+// from being usable if the table leaks
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
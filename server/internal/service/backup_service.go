@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ymb-cloz/internal/store"
+)
+
+// BackupVersion is written to every backup, restore only accepts versions it knows
+const BackupVersion = 1
+
+var ErrUnsupportedBackup = errors.New("unsupported backup version")
+
+// BackupIntegrityError lists the problems that keep a backup from being restored
+type BackupIntegrityError struct {
+	Problems []string
+}
+
+func (e *BackupIntegrityError) Error() string {
+	return "backup is inconsistent: " + strings.Join(e.Problems, "; ")
+}
+
+type BackupService struct {
+	store   *store.BackupStore
+	ratings *RatingService
+}
+
+func NewBackupService(store *store.BackupStore, ratings *RatingService) *BackupService {
+	return &BackupService{store: store, ratings: ratings}
+}
+
+func (s *BackupService) Backup() (*store.Backup, error) {
+	backup, err := s.store.Dump()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database: %v", err)
+	}
+	backup.Version = BackupVersion
+	backup.CreatedAt = time.Now().UTC()
+	return backup, nil
+}
+
+// Restore loads a backup into an empty database with the original IDs and
+// rebuilds the ratings from the restored games
+func (s *BackupService) Restore(backup *store.Backup) error {
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBackup, backup.Version)
+	}
+	if problems := CheckBackup(backup); len(problems) > 0 {
+		return &BackupIntegrityError{Problems: problems}
+	}
+
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.store.RestoreTx(tx, backup); err != nil {
+		if err == store.ErrDatabaseNotEmpty {
+			return err
+		}
+		return fmt.Errorf("failed to restore backup: %v", err)
+	}
+
+	mismatches, err := s.store.GamesPlayedMismatchesTx(tx)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) > 0 {
+		return &BackupIntegrityError{Problems: []string{"games_played does not match games of " + strings.Join(mismatches, ", ")}}
+	}
+
+	if err := s.ratings.RecomputeTx(tx); err != nil {
+		return fmt.Errorf("failed to recompute ratings: %v", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// CheckBackup returns every reference in the backup that does not resolve and
+// every player whose games_played differs from the games they appear in
+func CheckBackup(backup *store.Backup) []string {
+	var problems []string
+
+	players := make(map[string]string)
+	nicknames := make(map[string]bool)
+	for _, p := range backup.Players {
+		if _, ok := players[p.ID]; ok {
+			problems = append(problems, fmt.Sprintf("player %s appears twice", p.ID))
+		}
+		if nicknames[p.Nickname] {
+			problems = append(problems, fmt.Sprintf("nickname %s is used twice", p.Nickname))
+		}
+		players[p.ID] = p.Nickname
+		nicknames[p.Nickname] = true
+	}
+
+	seasons := make(map[string]bool)
+	for _, season := range backup.Seasons {
+		seasons[season.ID] = true
+	}
+
+	games := make(map[string]bool)
+	for _, g := range backup.Games {
+		if games[g.ID] {
+			problems = append(problems, fmt.Sprintf("game %s appears twice", g.ID))
+		}
+		games[g.ID] = true
+		if g.SeasonID != nil && !seasons[*g.SeasonID] {
+			problems = append(problems, fmt.Sprintf("game %s belongs to unknown season %s", g.ID, *g.SeasonID))
+		}
+	}
+
+	for _, a := range backup.Aliases {
+		if _, ok := players[a.PlayerID]; !ok {
+			problems = append(problems, fmt.Sprintf("alias %s belongs to unknown player %s", a.Alias, a.PlayerID))
+		}
+	}
+	for _, st := range backup.SeasonStandings {
+		if !seasons[st.SeasonID] {
+			problems = append(problems, fmt.Sprintf("standings of %s belong to unknown season %s", st.Nickname, st.SeasonID))
+		}
+	}
+
+	// Games each player actually appears in
+	played := make(map[string]map[string]bool)
+	for _, gp := range backup.GamePlayers {
+		if !games[gp.GameID] {
+			problems = append(problems, fmt.Sprintf("unknown game %s has players", gp.GameID))
+		}
+		if _, ok := players[gp.PlayerID]; !ok {
+			problems = append(problems, fmt.Sprintf("game %s has unknown player %s", gp.GameID, gp.PlayerID))
+			continue
+		}
+		if played[gp.PlayerID] == nil {
+			played[gp.PlayerID] = make(map[string]bool)
+		}
+		if played[gp.PlayerID][gp.GameID] {
+			problems = append(problems, fmt.Sprintf("%s appears twice in game %s", players[gp.PlayerID], gp.GameID))
+		}
+		played[gp.PlayerID][gp.GameID] = true
+	}
+
+	for _, p := range backup.Players {
+		listed := make(map[string]bool)
+		for _, gameID := range p.GamesPlayed {
+			if listed[gameID] {
+				problems = append(problems, fmt.Sprintf("games_played of %s lists game %s twice", p.Nickname, gameID))
+			}
+			listed[gameID] = true
+		}
+
+		var missing, extra []string
+		for gameID := range played[p.ID] {
+			if !listed[gameID] {
+				missing = append(missing, gameID)
+			}
+		}
+		for gameID := range listed {
+			if !played[p.ID][gameID] {
+				extra = append(extra, gameID)
+			}
+		}
+		sort.Strings(missing)
+		sort.Strings(extra)
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("games_played of %s is missing %s", p.Nickname, strings.Join(missing, ", ")))
+		}
+		if len(extra) > 0 {
+			problems = append(problems, fmt.Sprintf("games_played of %s lists games without them: %s", p.Nickname, strings.Join(extra, ", ")))
+		}
+	}
+
+	for _, pg := range backup.PendingGames {
+		if !json.Valid(pg.RadiantPlayers) || !json.Valid(pg.DirePlayers) {
+			problems = append(problems, fmt.Sprintf("pending game %s has invalid rosters", pg.ID))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"ymb-cloz/internal/models"
+	"ymb-cloz/internal/store"
+)
+
+var Roles = []models.Role{models.Carry, models.Mid, models.Offlane, models.Pos4, models.Pos5}
+
+const (
+	// Win rate prior: every role starts as if it had been played this many times at 50%
+	rolePriorGames = 5.0
+	// Rating points a 100% role win rate is worth over a 50% one
+	roleWinRateWeight = 200.0
+	// Penalty for a role the player has never played, fading out after roleExperienceGames
+	offRolePenalty      = 75.0
+	roleExperienceGames = 10.0
+	// Penalty for putting a player on a role they did not ask for
+	preferencePenalty = 150.0
+	// How many points of imbalance one point of role fit is worth
+	fitWeight = 0.05
+)
+
+type BalanceRequest struct {
+	PlayerIDs      []string                 `json:"player_ids"`
+	PreferredRoles map[string][]models.Role `json:"preferred_roles"`
+	Captains       []string                 `json:"captains"`
+}
+
+type BalancedPlayer struct {
+	PlayerID  string      `json:"player_id"`
+	Nickname  string      `json:"nickname"`
+	Role      models.Role `json:"role"`
+	IsCaptain bool        `json:"is_captain"`
+	Rating    float64     `json:"rating"`
+	Strength  float64     `json:"strength"`
+}
+
+type BalancedTeam struct {
+	Players  []BalancedPlayer `json:"players"`
+	Strength float64          `json:"strength"`
+}
+
+type BalanceResult struct {
+	Radiant               BalancedTeam `json:"radiant"`
+	Dire                  BalancedTeam `json:"dire"`
+	StrengthDiff          float64      `json:"strength_diff"`
+	RadiantWinProbability float64      `json:"radiant_win_probability"`
+}
+
+type BalanceService struct {
+	players *store.PlayerStore
+	ratings *store.RatingStore
+}
+
+func NewBalanceService(players *store.PlayerStore, ratings *store.RatingStore) *BalanceService {
+	return &BalanceService{players: players, ratings: ratings}
+}
+
+// Validate checks the player list, preferred roles and captains of a balance request
+func (req *BalanceRequest) Validate() error {
+	if len(req.PlayerIDs) != 10 {
+		return errors.New("exactly 10 players are required")
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range req.PlayerIDs {
+		if seen[id] {
+			return fmt.Errorf("player %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+
+	for id, roles := range req.PreferredRoles {
+		if !seen[id] {
+			return fmt.Errorf("preferred roles given for unknown player %s", id)
+		}
+		for _, role := range roles {
+			if !isRole(role) {
+				return fmt.Errorf("invalid role: %s", role)
+			}
+		}
+	}
+
+	if len(req.Captains) > 2 {
+		return errors.New("at most 2 captains can be set")
+	}
+	for _, id := range req.Captains {
+		if !seen[id] {
+			return fmt.Errorf("captain %s is not one of the players", id)
+		}
+	}
+	if len(req.Captains) == 2 && req.Captains[0] == req.Captains[1] {
+		return errors.New("captains must be different players")
+	}
+
+	return nil
+}
+
+func isRole(role models.Role) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// balancePlayer holds everything the search needs about one player
+type balancePlayer struct {
+	id        string
+	nickname  string
+	rating    float64
+	strength  map[models.Role]float64
+	preferred map[models.Role]bool
+	captain   bool
+}
+
+// fit is the strength of the player on a role, minus the preference penalty
+func (p *balancePlayer) fit(role models.Role) float64 {
+	fit := p.strength[role]
+	if len(p.preferred) > 0 && !p.preferred[role] {
+		fit -= preferencePenalty
+	}
+	return fit
+}
+
+// Balance finds the Radiant/Dire split and role assignment with the smallest
+// strength difference, preferring assignments that fit players best
+func (s *BalanceService) Balance(req *BalanceRequest) (*BalanceResult, error) {
+	players, err := s.loadPlayers(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *BalanceResult
+	bestCost := math.Inf(1)
+
+	// Player 0 is always Radiant so every split is only considered once
+	for mask := 0; mask < 1<<10; mask++ {
+		if mask&1 == 0 || popcount(mask) != 5 {
+			continue
+		}
+
+		var radiant, dire []*balancePlayer
+		for i, p := range players {
+			if mask&(1<<i) != 0 {
+				radiant = append(radiant, p)
+			} else {
+				dire = append(dire, p)
+			}
+		}
+
+		if !captainsSeparated(radiant, dire) {
+			continue
+		}
+
+		radiantRoles, radiantFit := bestRoles(radiant)
+		direRoles, direFit := bestRoles(dire)
+		radiantStrength := teamStrength(radiant, radiantRoles)
+		direStrength := teamStrength(dire, direRoles)
+
+		// Trade a little balance for players getting roles they are good at and asked for
+		cost := math.Abs(radiantStrength-direStrength) - fitWeight*(radiantFit+direFit)
+		if cost < bestCost {
+			bestCost = cost
+			best = &BalanceResult{
+				Radiant:      buildTeam(radiant, radiantRoles, radiantStrength),
+				Dire:         buildTeam(dire, direRoles, direStrength),
+				StrengthDiff: radiantStrength - direStrength,
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, errors.New("no valid split found")
+	}
+
+	// Elo expectation on the average player strength of each team
+	best.RadiantWinProbability = 1 / (1 + math.Pow(10, -best.StrengthDiff/5/400))
+	return best, nil
+}
+
+func (s *BalanceService) loadPlayers(req *BalanceRequest) ([]*balancePlayer, error) {
+	ratings, err := s.ratings.GetRatings(req.PlayerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ratings: %v", err)
+	}
+
+	roleStats, err := s.players.GetRoleStats(req.PlayerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role stats: %v", err)
+	}
+	byPlayer := make(map[string]map[models.Role]store.RoleStats)
+	for _, stat := range roleStats {
+		if byPlayer[stat.PlayerID] == nil {
+			byPlayer[stat.PlayerID] = make(map[models.Role]store.RoleStats)
+		}
+		byPlayer[stat.PlayerID][models.Role(stat.Role)] = stat
+	}
+
+	captains := make(map[string]bool)
+	for _, id := range req.Captains {
+		captains[id] = true
+	}
+
+	players := make([]*balancePlayer, 0, len(req.PlayerIDs))
+	for _, id := range req.PlayerIDs {
+		player, err := s.players.GetPlayer(id)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load player: %v", err)
+		}
+
+		rating := DefaultRating
+		if r, ok := ratings[id]; ok {
+			rating = r.Rating
+		}
+
+		p := &balancePlayer{
+			id:        id,
+			nickname:  player.Nickname,
+			rating:    rating,
+			strength:  make(map[models.Role]float64),
+			preferred: make(map[models.Role]bool),
+			captain:   captains[id],
+		}
+		for _, role := range Roles {
+			p.strength[role] = rating + roleAdjustment(byPlayer[id][role])
+		}
+		for _, role := range req.PreferredRoles[id] {
+			p.preferred[role] = true
+		}
+
+		players = append(players, p)
+	}
+
+	return players, nil
+}
+
+// roleAdjustment shifts the overall rating by how well the player does on a role
+func roleAdjustment(stat store.RoleStats) float64 {
+	games := float64(stat.Games)
+	winRate := (float64(stat.Wins) + rolePriorGames/2) / (games + rolePriorGames)
+	experience := math.Min(games, roleExperienceGames) / roleExperienceGames
+	return (winRate-0.5)*roleWinRateWeight - offRolePenalty*(1-experience)
+}
+
+func captainsSeparated(radiant, dire []*balancePlayer) bool {
+	count := func(team []*balancePlayer) int {
+		n := 0
+		for _, p := range team {
+			if p.captain {
+				n++
+			}
+		}
+		return n
+	}
+	return count(radiant) <= 1 && count(dire) <= 1
+}
+
+// bestRoles tries every permutation of roles and returns the one with the highest total fit
+func bestRoles(team []*balancePlayer) ([]models.Role, float64) {
+	var best []models.Role
+	bestFit := math.Inf(-1)
+
+	roles := make([]models.Role, len(Roles))
+	copy(roles, Roles)
+	permute(roles, 0, func(perm []models.Role) {
+		fit := 0.0
+		for i, p := range team {
+			fit += p.fit(perm[i])
+		}
+		if fit > bestFit {
+			bestFit = fit
+			best = append([]models.Role(nil), perm...)
+		}
+	})
+
+	return best, bestFit
+}
+
+func permute(roles []models.Role, k int, visit func([]models.Role)) {
+	if k == len(roles) {
+		visit(roles)
+		return
+	}
+	for i := k; i < len(roles); i++ {
+		roles[k], roles[i] = roles[i], roles[k]
+		permute(roles, k+1, visit)
+		roles[k], roles[i] = roles[i], roles[k]
+	}
+}
+
+func teamStrength(team []*balancePlayer, roles []models.Role) float64 {
+	total := 0.0
+	for i, p := range team {
+		total += p.strength[roles[i]]
+	}
+	return total
+}
+
+func buildTeam(team []*balancePlayer, roles []models.Role, strength float64) BalancedTeam {
+	result := BalancedTeam{Strength: strength}
+	for _, role := range Roles {
+		for i, p := range team {
+			if roles[i] != role {
+				continue
+			}
+			result.Players = append(result.Players, BalancedPlayer{
+				PlayerID:  p.id,
+				Nickname:  p.nickname,
+				Role:      role,
+				IsCaptain: p.captain,
+				Rating:    p.rating,
+				Strength:  p.strength[role],
+			})
+		}
+	}
+	return result
+}
+
+func popcount(mask int) int {
+	count := 0
+	for ; mask > 0; mask &= mask - 1 {
+		count++
+	}
+	return count
+}
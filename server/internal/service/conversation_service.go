@@ -0,0 +1,49 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ymb-cloz/internal/store"
+)
+
+// Conversations left alone for longer than this are forgotten
+const conversationTTL = 24 * time.Hour
+
+// ConversationService keeps the state of multi-step bot conversations per chat and user
+type ConversationService struct {
+	store *store.ConversationStore
+}
+
+func NewConversationService(store *store.ConversationStore) *ConversationService {
+	return &ConversationService{store: store}
+}
+
+// Load decodes the saved state into state, false if there is no recent conversation
+func (s *ConversationService) Load(chatID, userID int64, kind string, state interface{}) (bool, error) {
+	raw, err := s.store.GetConversation(chatID, userID, kind, conversationTTL)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load conversation: %v", err)
+	}
+	if err := json.Unmarshal(raw, state); err != nil {
+		return false, fmt.Errorf("failed to decode conversation: %v", err)
+	}
+	return true, nil
+}
+
+func (s *ConversationService) Save(chatID, userID int64, kind string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %v", err)
+	}
+	return s.store.SaveConversation(chatID, userID, kind, raw)
+}
+
+func (s *ConversationService) Delete(chatID, userID int64, kind string) error {
+	return s.store.DeleteConversation(chatID, userID, kind, conversationTTL)
+}
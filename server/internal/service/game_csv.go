@@ -0,0 +1,530 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ymb-cloz/internal/models"
+	"ymb-cloz/internal/store"
+)
+
+// Games CSV layout, one row per player in a game. The header row is required,
+// columns may come in any order and names are case-insensitive.
+//
+//	game         required, any reference shared by the 10 rows of a game (export uses the game ID)
+//	start_time   required, RFC 3339 ("2024-03-01T19:30:00+03:00") or UTC "2024-03-01 16:30"
+//	end_time     optional, same formats
+//	winner       required, RADIANT or DIRE
+//	match_id     optional Dota match ID
+//	team         required, RADIANT or DIRE
+//	nickname     required, unknown nicknames create new players
+//	role         required, carry, mid, offlane, pos4 or pos5
+//	is_captain   true/false, yes/no or 1/0, empty means false
+//	create_new   optional boolean, creates the player even if the nickname looks like an existing one
+//	hero         optional hero name or abbreviation
+//	kills, deaths, assists                optional, all three or none
+//	last_hits, net_worth, hero_damage     optional
+//
+// Game columns only need to be filled on one row of a game, but must not
+// disagree when repeated.
+var GamesCSVColumns = []string{
+	"game", "start_time", "end_time", "winner", "match_id",
+	"team", "nickname", "role", "is_captain", "create_new", "hero",
+	"kills", "deaths", "assists", "last_hits", "net_worth", "hero_damage",
+}
+
+var requiredCSVColumns = []string{"game", "start_time", "winner", "team", "nickname", "role"}
+
+// Accepted start_time and end_time formats, the ones without a zone are UTC
+var csvTimeFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+var ErrInvalidCSV = errors.New("invalid CSV")
+
+// CSVRowError points at the line of the file a problem was found on
+type CSVRowError struct {
+	Line  int    `json:"line"`
+	Game  string `json:"game,omitempty"`
+	Error string `json:"error"`
+}
+
+// CSVImportResult reports what an import did or, on a dry run or with errors, would do.
+// Nothing is saved when Errors is not empty.
+type CSVImportResult struct {
+	DryRun bool          `json:"dry_run"`
+	Games  int           `json:"games"`
+	Errors []CSVRowError `json:"errors,omitempty"`
+}
+
+// csvGame is a game assembled from the rows sharing its reference
+type csvGame struct {
+	ref     string
+	line    int
+	start   string
+	end     string
+	winner  string
+	matchID string
+	req     CreateGameRequest
+	// lines of the rows each player came from, in roster order
+	radiantLines []int
+	direLines    []int
+	// lowercased nicknames seen in this game and their lines
+	nicknames map[string]int
+	// invalid games are not checked as a whole, their row errors say enough
+	invalid bool
+}
+
+// ImportGamesCSV records all games of a CSV file in one transaction, or none of
+// them when any row fails. Ratings are replayed once at the end and game
+// listeners are not notified about imported games.
+func (s *gameService) ImportGamesCSV(r io.Reader, dryRun bool) (*CSVImportResult, error) {
+	games, rowErrors, err := parseGamesCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	result := &CSVImportResult{DryRun: dryRun, Games: len(games), Errors: rowErrors}
+	if len(games) == 0 && len(rowErrors) == 0 {
+		return nil, fmt.Errorf("%w: no games found", ErrInvalidCSV)
+	}
+
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	checkErrors, err := s.checkCSVGamesTx(tx, games)
+	if err != nil {
+		return nil, err
+	}
+	result.Errors = append(result.Errors, checkErrors...)
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	for _, g := range games {
+		_, _, err := s.createGameTx(tx, &g.req)
+
+		// The checks above should catch these, players created earlier in the file aside
+		var conflict *NicknameConflictError
+		if errors.As(err, &conflict) || errors.Is(err, ErrPlayerListedTwice) {
+			result.Errors = append(result.Errors, CSVRowError{Line: g.line, Game: g.ref, Error: err.Error()})
+			continue
+		}
+		if err == ErrMatchAlreadyRecorded {
+			// The failed insert aborted the transaction, later games cannot be checked
+			result.Errors = append(result.Errors, CSVRowError{Line: g.line, Game: g.ref, Error: err.Error()})
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to import game %s: %v", g.ref, err)
+		}
+	}
+	if len(result.Errors) > 0 || dryRun {
+		return result, nil
+	}
+
+	if err := s.ratings.RecomputeTx(tx); err != nil {
+		return nil, fmt.Errorf("failed to recompute ratings: %v", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return result, nil
+}
+
+// parseGamesCSV groups rows into validated game requests ordered by start time.
+// Problems with individual rows are collected rather than stopping the parse.
+func parseGamesCSV(r io.Reader) ([]*csvGame, []CSVRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: file is empty", ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isCSVColumn(name) {
+			return nil, nil, fmt.Errorf("%w: unknown column %q", ErrInvalidCSV, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidCSV, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidCSV, name)
+		}
+	}
+
+	var rowErrors []CSVRowError
+	var games []*csvGame
+	byRef := make(map[string]*csvGame)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCSV, parseErr.Line, parseErr.Err)
+			}
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		// Spreadsheets tend to leave empty rows behind
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		ref := field("game")
+		if ref == "" {
+			rowErrors = append(rowErrors, CSVRowError{Line: line, Error: "game is required"})
+			continue
+		}
+		game, ok := byRef[ref]
+		if !ok {
+			game = &csvGame{ref: ref, line: line}
+			byRef[ref] = game
+			games = append(games, game)
+		}
+
+		if err := game.addRow(line, field); err != nil {
+			game.invalid = true
+			rowErrors = append(rowErrors, CSVRowError{Line: line, Game: ref, Error: err.Error()})
+		}
+	}
+
+	for _, game := range games {
+		if game.invalid {
+			continue
+		}
+		if err := game.finish(); err != nil {
+			rowErrors = append(rowErrors, CSVRowError{Line: game.line, Game: game.ref, Error: err.Error()})
+		}
+	}
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Line < rowErrors[j].Line
+	})
+
+	// Insert in the order the games were played so games_played stays chronological
+	sort.SliceStable(games, func(i, j int) bool {
+		a, b := games[i].req.StartTime, games[j].req.StartTime
+		return a != nil && b != nil && a.Before(*b)
+	})
+
+	return games, rowErrors, nil
+}
+
+func isCSVColumn(name string) bool {
+	for _, c := range GamesCSVColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addRow adds the player on a row to the game and records its game columns
+func (g *csvGame) addRow(line int, field func(string) string) error {
+	for _, c := range []struct {
+		name  string
+		value *string
+	}{
+		{"start_time", &g.start},
+		{"end_time", &g.end},
+		{"winner", &g.winner},
+		{"match_id", &g.matchID},
+	} {
+		value := field(c.name)
+		if c.name == "winner" {
+			value = strings.ToUpper(value)
+		}
+		if value == "" {
+			continue
+		}
+		if *c.value != "" && *c.value != value {
+			return fmt.Errorf("%s %q differs from %q on another row of this game", c.name, value, *c.value)
+		}
+		*c.value = value
+	}
+
+	nickname := field("nickname")
+	if nickname == "" {
+		return errors.New("nickname is required")
+	}
+	if g.nicknames == nil {
+		g.nicknames = make(map[string]int)
+	}
+	if other, ok := g.nicknames[strings.ToLower(nickname)]; ok {
+		return fmt.Errorf("%v: %s is also on line %d", ErrPlayerListedTwice, nickname, other)
+	}
+	g.nicknames[strings.ToLower(nickname)] = line
+	input := GamePlayerInput{
+		Nickname: &nickname,
+		Role:     strings.ToLower(field("role")),
+	}
+
+	var err error
+	if input.IsCaptain, err = parseCSVBool(field("is_captain")); err != nil {
+		return fmt.Errorf("is_captain: %v", err)
+	}
+	if input.CreateNew, err = parseCSVBool(field("create_new")); err != nil {
+		return fmt.Errorf("create_new: %v", err)
+	}
+	if hero := field("hero"); hero != "" {
+		input.Hero = &hero
+	}
+	if input.Stats, err = parseCSVStats(field); err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(field("team")) {
+	case "RADIANT":
+		g.req.RadiantPlayers = append(g.req.RadiantPlayers, input)
+		g.radiantLines = append(g.radiantLines, line)
+	case "DIRE":
+		g.req.DirePlayers = append(g.req.DirePlayers, input)
+		g.direLines = append(g.direLines, line)
+	default:
+		return fmt.Errorf("team must be either RADIANT or DIRE, got %q", field("team"))
+	}
+	return nil
+}
+
+// finish fills in the game columns and runs the same checks as a single game
+func (g *csvGame) finish() error {
+	if g.start == "" {
+		return errors.New("start_time is required")
+	}
+	start, err := parseCSVTime(g.start)
+	if err != nil {
+		return fmt.Errorf("start_time: %v", err)
+	}
+	g.req.StartTime = &start
+
+	if g.end != "" {
+		end, err := parseCSVTime(g.end)
+		if err != nil {
+			return fmt.Errorf("end_time: %v", err)
+		}
+		g.req.EndTime = &end
+	}
+
+	if g.matchID != "" {
+		matchID, err := strconv.ParseInt(g.matchID, 10, 64)
+		if err != nil || matchID <= 0 {
+			return errors.New("match_id must be a positive number")
+		}
+		g.req.MatchID = &matchID
+	}
+
+	g.req.Winner = g.winner
+	return g.req.Validate()
+}
+
+// checkCSVGamesTx looks up the players and matches of the parsed games without
+// saving anything, so a file is reported in full before the first insert. Each
+// problem points at the row of the player it is about.
+func (s *gameService) checkCSVGamesTx(tx *sql.Tx, games []*csvGame) ([]CSVRowError, error) {
+	names, err := s.store.ListPlayerNamesTx(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load player names: %v", err)
+	}
+
+	var rowErrors []CSVRowError
+	matches := make(map[int64]string)
+	for _, g := range games {
+		if matchID := g.req.MatchID; matchID != nil {
+			if other, ok := matches[*matchID]; ok {
+				rowErrors = append(rowErrors, CSVRowError{Line: g.line, Game: g.ref, Error: fmt.Sprintf("match_id %d is also used by game %s", *matchID, other)})
+			}
+			matches[*matchID] = g.ref
+
+			recorded, err := s.store.MatchRecordedTx(tx, *matchID)
+			if err != nil {
+				return nil, err
+			}
+			if recorded {
+				rowErrors = append(rowErrors, CSVRowError{Line: g.line, Game: g.ref, Error: ErrMatchAlreadyRecorded.Error()})
+			}
+		}
+
+		// Nicknames and aliases of the same player only show up once resolved
+		seen := make(map[string]string)
+		players := append(append([]GamePlayerInput{}, g.req.RadiantPlayers...), g.req.DirePlayers...)
+		lines := append(append([]int{}, g.radiantLines...), g.direLines...)
+		for i, p := range players {
+			nickname := *p.Nickname
+			playerID, err := s.store.FindPlayerByNicknameTx(tx, nickname)
+			if err == sql.ErrNoRows {
+				if similar := findSimilarPlayers(names, nickname); !p.CreateNew && len(similar) > 0 {
+					rowErrors = append(rowErrors, CSVRowError{Line: lines[i], Game: g.ref, Error: fmt.Sprintf("player %q not found, did you mean %s? Set create_new to add a new player", nickname, similar[0].Nickname)})
+				}
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up player %s: %v", nickname, err)
+			}
+
+			if other, ok := seen[playerID]; ok {
+				rowErrors = append(rowErrors, CSVRowError{Line: lines[i], Game: g.ref, Error: fmt.Sprintf("%v: %s and %s are the same player", ErrPlayerListedTwice, other, nickname)})
+				continue
+			}
+			seen[playerID] = nickname
+		}
+	}
+	return rowErrors, nil
+}
+
+func parseCSVTime(value string) (time.Time, error) {
+	for _, layout := range csvTimeFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected e.g. 2024-03-01T19:30:00+03:00 or 2024-03-01 16:30", value)
+}
+
+func parseCSVBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "false", "no", "0":
+		return false, nil
+	case "true", "yes", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", value)
+}
+
+// parseCSVStats reads the optional performance columns, nil when K/D/A is empty
+func parseCSVStats(field func(string) string) (*models.PerformanceStats, error) {
+	values := make(map[string]*int)
+	for _, name := range []string{"kills", "deaths", "assists", "last_hits", "net_worth", "hero_damage"} {
+		raw := field(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, got %q", name, raw)
+		}
+		values[name] = &value
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	kills, deaths, assists := values["kills"], values["deaths"], values["assists"]
+	if kills == nil || deaths == nil || assists == nil {
+		return nil, errors.New("kills, deaths and assists must be given together")
+	}
+	return &models.PerformanceStats{
+		Kills:      *kills,
+		Deaths:     *deaths,
+		Assists:    *assists,
+		LastHits:   values["last_hits"],
+		NetWorth:   values["net_worth"],
+		HeroDamage: values["hero_damage"],
+	}, nil
+}
+
+// Games are exported in pages of this size
+const csvExportPageSize = 500
+
+// ExportGamesCSV writes all games, oldest first, in the layout ImportGamesCSV reads
+func (s *gameService) ExportGamesCSV(w io.Writer) error {
+	var games []models.Game
+	for offset := 0; ; offset += csvExportPageSize {
+		page, err := s.ListGames(store.GameFilter{Limit: csvExportPageSize, Offset: offset})
+		if err != nil {
+			return err
+		}
+		games = append(games, page.Games...)
+		if len(games) >= page.Total || len(page.Games) == 0 {
+			break
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(GamesCSVColumns); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+
+	for i := len(games) - 1; i >= 0; i-- {
+		game := games[i]
+		for _, p := range append(append([]models.GamePlayer{}, game.RadiantTeam...), game.DireTeam...) {
+			if err := writer.Write(csvRecord(game, p)); err != nil {
+				return fmt.Errorf("failed to write CSV: %v", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvRecord builds the row of one player, in GamesCSVColumns order
+func csvRecord(game models.Game, p models.GamePlayer) []string {
+	optional := func(value *int) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.Itoa(*value)
+	}
+
+	endTime, matchID := "", ""
+	if game.EndTime != nil {
+		endTime = game.EndTime.UTC().Format(time.RFC3339)
+	}
+	if game.MatchID != nil {
+		matchID = strconv.FormatInt(*game.MatchID, 10)
+	}
+
+	var kills, deaths, assists, lastHits, netWorth, heroDamage string
+	if p.Stats != nil {
+		kills = strconv.Itoa(p.Stats.Kills)
+		deaths = strconv.Itoa(p.Stats.Deaths)
+		assists = strconv.Itoa(p.Stats.Assists)
+		lastHits = optional(p.Stats.LastHits)
+		netWorth = optional(p.Stats.NetWorth)
+		heroDamage = optional(p.Stats.HeroDamage)
+	}
+
+	return []string{
+		game.ID, game.StartTime.UTC().Format(time.RFC3339), endTime, game.Winner, matchID,
+		p.Team, p.Nickname, string(p.Role), strconv.FormatBool(p.IsCaptain), "", p.Hero,
+		kills, deaths, assists, lastHits, netWorth, heroDamage,
+	}
+}
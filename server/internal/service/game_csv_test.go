@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCSVHeader = []string{"game", "start_time", "winner", "team", "nickname", "role", "is_captain", "kills", "deaths", "assists"}
+
+// testCSVGame returns the 10 rows of a valid game, game columns on the first row only
+func testCSVGame(ref, start, prefix string) [][]string {
+	roles := []string{"carry", "mid", "offlane", "pos4", "pos5"}
+	var rows [][]string
+	for i := 0; i < 10; i++ {
+		team := "RADIANT"
+		if i >= 5 {
+			team = "DIRE"
+		}
+		row := []string{ref, "", "", team, prefix + string(rune('a'+i)), roles[i%5], "", "", "", ""}
+		if i == 0 {
+			row[1], row[2] = start, "RADIANT"
+		}
+		if i%5 == 0 {
+			row[6] = "yes"
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func writeTestCSV(t *testing.T, rows [][]string) string {
+	t.Helper()
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.WriteAll(append([][]string{testCSVHeader}, rows...)); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	return b.String()
+}
+
+func TestParseGamesCSV(t *testing.T) {
+	rows := append(testCSVGame("g1", "2024-03-02 18:00", "x"), testCSVGame("g2", "2024-03-01T19:30:00+03:00", "y")...)
+	rows[3][7], rows[3][8], rows[3][9] = "10", "2", "7"
+
+	games, rowErrors, err := parseGamesCSV(strings.NewReader(writeTestCSV(t, rows)))
+	if err != nil {
+		t.Fatalf("parseGamesCSV: %v", err)
+	}
+	if len(rowErrors) > 0 {
+		t.Fatalf("unexpected row errors: %+v", rowErrors)
+	}
+	if len(games) != 2 || games[0].ref != "g2" || games[1].ref != "g1" {
+		t.Fatalf("got %d games, want g2 and g1 ordered by start time", len(games))
+	}
+
+	g1 := games[1]
+	if want := time.Date(2024, 3, 2, 18, 0, 0, 0, time.UTC); !g1.req.StartTime.Equal(want) {
+		t.Errorf("start time = %v, want %v", g1.req.StartTime, want)
+	}
+	if g1.req.Winner != "RADIANT" || len(g1.req.RadiantPlayers) != 5 || len(g1.req.DirePlayers) != 5 {
+		t.Errorf("g1 = %+v, want a Radiant win with 5 players per team", g1.req)
+	}
+	if !g1.req.RadiantPlayers[0].IsCaptain || g1.req.RadiantPlayers[1].IsCaptain {
+		t.Errorf("only the first Radiant player should be captain")
+	}
+	if stats := g1.req.RadiantPlayers[3].Stats; stats == nil || stats.Kills != 10 || stats.Deaths != 2 || stats.Assists != 7 {
+		t.Errorf("stats = %+v, want 10/2/7", stats)
+	}
+	if !reflect.DeepEqual(g1.direLines, []int{7, 8, 9, 10, 11}) {
+		t.Errorf("Dire lines = %v, want 7-11", g1.direLines)
+	}
+}
+
+func TestParseGamesCSVHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty file", "", "invalid CSV: file is empty"},
+		{"unknown column", "game,start_time,winner,team,nickname,role,mmr\n", `invalid CSV: unknown column "mmr"`},
+		{"duplicate column", "game,start_time,winner,team,nickname,role,Role\n", `invalid CSV: duplicate column "role"`},
+		{"missing column", "game,start_time,winner,team,nickname\n", `invalid CSV: missing column "role"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseGamesCSV(strings.NewReader(tt.text))
+			if !errors.Is(err, ErrInvalidCSV) || err.Error() != tt.want {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGamesCSVRowErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(rows [][]string)
+		want   []CSVRowError
+	}{
+		{
+			name:   "unknown team",
+			modify: func(rows [][]string) { rows[1][3] = "GREEN" },
+			want:   []CSVRowError{{Line: 3, Game: "g1", Error: `team must be either RADIANT or DIRE, got "GREEN"`}},
+		},
+		{
+			name:   "player listed twice",
+			modify: func(rows [][]string) { rows[6][4] = "A" },
+			want:   []CSVRowError{{Line: 8, Game: "g1", Error: "a player can only play once per game: A is also on line 2"}},
+		},
+		{
+			name:   "game columns disagree",
+			modify: func(rows [][]string) { rows[4][1] = "2024-03-02 19:00" },
+			want:   []CSVRowError{{Line: 6, Game: "g1", Error: `start_time "2024-03-02 19:00" differs from "2024-03-02 18:00" on another row of this game`}},
+		},
+		{
+			name:   "partial stats",
+			modify: func(rows [][]string) { rows[2][7] = "5" },
+			want:   []CSVRowError{{Line: 4, Game: "g1", Error: "kills, deaths and assists must be given together"}},
+		},
+		{
+			name:   "invalid start time",
+			modify: func(rows [][]string) { rows[0][1] = "yesterday" },
+			want:   []CSVRowError{{Line: 2, Game: "g1", Error: `start_time: invalid time "yesterday", expected e.g. 2024-03-01T19:30:00+03:00 or 2024-03-01 16:30`}},
+		},
+		{
+			name:   "missing winner",
+			modify: func(rows [][]string) { rows[0][2] = "" },
+			want:   []CSVRowError{{Line: 2, Game: "g1", Error: "winner must be either RADIANT or DIRE"}},
+		},
+		{
+			name:   "short team",
+			modify: func(rows [][]string) { rows[9] = []string{"", "", "", "", "", "", "", "", "", ""} },
+			want:   []CSVRowError{{Line: 2, Game: "g1", Error: "each team must have exactly 5 players"}},
+		},
+		{
+			name:   "missing game reference",
+			modify: func(rows [][]string) { rows[9][0] = "" },
+			want: []CSVRowError{
+				{Line: 2, Game: "g1", Error: "each team must have exactly 5 players"},
+				{Line: 11, Error: "game is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := testCSVGame("g1", "2024-03-02 18:00", "")
+			tt.modify(rows)
+
+			_, rowErrors, err := parseGamesCSV(strings.NewReader(writeTestCSV(t, rows)))
+			if err != nil {
+				t.Fatalf("parseGamesCSV: %v", err)
+			}
+			if !reflect.DeepEqual(rowErrors, tt.want) {
+				t.Errorf("row errors = %+v, want %+v", rowErrors, tt.want)
+			}
+		})
+	}
+}
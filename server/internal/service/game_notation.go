@@ -0,0 +1,462 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ymb-cloz/internal/heroes"
+)
+
+// Compact game notation, the way results are posted in chat:
+//
+//	R: miracle(c) carry, dendi mid, ceb offlane, puppey pos4, n0tail pos5
+//	D: ana 1, topson 2, jerax 3, saksa 4, kuroky(c) 5 | win D
+//
+// Sections are separated by new lines or "|" and may come in any order:
+//   - "R:" or "Radiant:" and "D:" or "Dire:" followed by five comma separated
+//     players written as "nick role [hero]"
+//   - "win R", "win D", "win Radiant" or "win Dire"
+//
+// Roles are carry, mid, offlane, pos4 and pos5, or positions 1 to 5 (also
+// pos1, pos2, pos3 and off). A nickname followed by "(c)" is the captain, one
+// followed by "(new)" is a new player even if the nickname looks like an
+// existing one. Keywords, roles and heroes are case-insensitive.
+
+var notationRoles = map[string]string{
+	"carry": "carry", "1": "carry", "pos1": "carry",
+	"mid": "mid", "2": "mid", "pos2": "mid",
+	"offlane": "offlane", "off": "offlane", "3": "offlane", "pos3": "offlane",
+	"pos4": "pos4", "4": "pos4",
+	"pos5": "pos5", "5": "pos5",
+}
+
+var notationSides = map[string]string{
+	"r": "RADIANT", "radiant": "RADIANT",
+	"d": "DIRE", "dire": "DIRE",
+}
+
+// NotationError points at a problem in the notation, lines and columns start at 1
+type NotationError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e NotationError) String() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// NotationErrors lists every problem found in the notation, in text order
+type NotationErrors []NotationError
+
+func (e NotationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.String()
+	}
+	return "invalid game notation: " + strings.Join(messages, "; ")
+}
+
+type notationToken struct {
+	text   string
+	offset int
+}
+
+type notationTeam struct {
+	line, offset int
+	players      []GamePlayerInput
+	roles        map[string]bool
+	captains     int
+}
+
+type notationParser struct {
+	lines  []string
+	errors NotationErrors
+	teams  map[string]*notationTeam
+	winner string
+	// Set by any win section, even an invalid one
+	hasWinner bool
+	// Nicknames already listed, to report players on both teams
+	nicknames map[string]string
+	// Heroes already picked and who picked them
+	picked map[int]string
+}
+
+// ParseGameNotation turns compact notation into a game request. On failure
+// the error is NotationErrors with every problem found.
+func ParseGameNotation(text string) (*CreateGameRequest, error) {
+	p := &notationParser{
+		lines:     strings.Split(strings.TrimPrefix(text, "\uFEFF"), "\n"),
+		teams:     make(map[string]*notationTeam),
+		nicknames: make(map[string]string),
+		picked:    make(map[int]string),
+	}
+
+	for i, line := range p.lines {
+		line = strings.TrimSuffix(line, "\r")
+		p.lines[i] = line
+
+		start := 0
+		for start <= len(line) {
+			end := strings.IndexByte(line[start:], '|')
+			if end < 0 {
+				end = len(line)
+			} else {
+				end += start
+			}
+			p.parseSection(i, start, end)
+			start = end + 1
+		}
+	}
+
+	last := len(p.lines) - 1
+	for _, side := range []string{"RADIANT", "DIRE"} {
+		team := p.teams[side]
+		if team == nil {
+			p.errorf(last, len(p.lines[last]), "missing %s team", sideTitle(side))
+			continue
+		}
+		if len(team.players) != 5 {
+			p.errorf(team.line, team.offset, "%s has %d players, expected 5", sideTitle(side), len(team.players))
+		}
+		if team.captains == 0 {
+			p.errorf(team.line, team.offset, "%s has no captain, mark one with (c)", sideTitle(side))
+		}
+	}
+	if !p.hasWinner {
+		p.errorf(last, len(p.lines[last]), "missing winner, e.g. \"win R\"")
+	}
+
+	if len(p.errors) > 0 {
+		sort.SliceStable(p.errors, func(i, j int) bool {
+			a, b := p.errors[i], p.errors[j]
+			return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+		})
+		return nil, p.errors
+	}
+
+	req := &CreateGameRequest{
+		RadiantPlayers: p.teams["RADIANT"].players,
+		DirePlayers:    p.teams["DIRE"].players,
+		Winner:         p.winner,
+	}
+	// The parser reports every rule of Validate at its position, this is a
+	// safety net for rules added there later
+	if err := req.Validate(); err != nil {
+		return nil, NotationErrors{{Line: 1, Column: 1, Message: err.Error()}}
+	}
+	return req, nil
+}
+
+// errorf records an error at a byte offset of a line
+func (p *notationParser) errorf(line, offset int, format string, args ...interface{}) {
+	p.errors = append(p.errors, NotationError{
+		Line:    line + 1,
+		Column:  utf8.RuneCountInString(p.lines[line][:offset]) + 1,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// parseSection parses line[start:end], a team, the winner or nothing at all
+func (p *notationParser) parseSection(line, start, end int) {
+	text := p.lines[line]
+	start = skipNotationSpaces(text, start, end)
+	if start == end {
+		return
+	}
+
+	keywordEnd := start
+	for keywordEnd < end {
+		r, size := utf8.DecodeRuneInString(text[keywordEnd:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		keywordEnd += size
+	}
+	keyword := strings.ToLower(text[start:keywordEnd])
+
+	rest := skipNotationSpaces(text, keywordEnd, end)
+
+	if side, ok := notationSides[keyword]; ok {
+		if rest == end || text[rest] != ':' {
+			p.errorf(line, rest, "expected \":\" after %s", text[start:keywordEnd])
+			return
+		}
+		p.parseTeam(side, line, start, rest+1, end)
+		return
+	}
+
+	if keyword == "win" || keyword == "winner" {
+		if rest < end && text[rest] == ':' {
+			rest++
+		}
+		p.parseWinner(line, start, rest, end)
+		return
+	}
+
+	p.errorf(line, start, "expected \"R:\", \"D:\" or \"win\", got %q", notationFields(text[start:end], start)[0].text)
+}
+
+func (p *notationParser) parseWinner(line, keyword, start, end int) {
+	if p.hasWinner {
+		p.errorf(line, keyword, "the winner is given twice")
+		return
+	}
+	p.hasWinner = true
+
+	tokens := notationFields(p.lines[line][start:end], start)
+	if len(tokens) == 0 {
+		p.errorf(line, end, "expected R or D after win")
+		return
+	}
+	side, ok := notationSides[strings.ToLower(tokens[0].text)]
+	if !ok {
+		p.errorf(line, tokens[0].offset, "unknown side %q, expected R or D", tokens[0].text)
+		return
+	}
+	if len(tokens) > 1 {
+		p.errorf(line, tokens[1].offset, "unexpected %q after the winner", tokens[1].text)
+	}
+	p.winner = side
+}
+
+func (p *notationParser) parseTeam(side string, line, label, start, end int) {
+	if p.teams[side] != nil {
+		p.errorf(line, label, "%s is listed twice", sideTitle(side))
+		return
+	}
+	team := &notationTeam{line: line, offset: label, roles: make(map[string]bool)}
+	p.teams[side] = team
+
+	text := p.lines[line]
+	for start <= end {
+		entryEnd := strings.IndexByte(text[start:end], ',')
+		if entryEnd < 0 {
+			entryEnd = end
+		} else {
+			entryEnd += start
+		}
+		p.parsePlayer(side, team, line, start, entryEnd)
+		start = entryEnd + 1
+	}
+}
+
+// parsePlayer parses "nick[(c)][(new)] role [hero]"
+func (p *notationParser) parsePlayer(side string, team *notationTeam, line, start, end int) {
+	text := p.lines[line]
+	tokens := notationFields(text[start:end], start)
+	if len(tokens) == 0 {
+		// Allows a trailing comma
+		return
+	}
+
+	nick := tokens[0]
+	input := GamePlayerInput{}
+
+	// Markers may be attached to the nickname or follow it as separate words
+	markers := []notationToken{}
+	if i := strings.IndexByte(nick.text, '('); i > 0 {
+		markers = append(markers, notationToken{text: nick.text[i:], offset: nick.offset + i})
+		nick.text = nick.text[:i]
+	}
+	tokens = tokens[1:]
+	for len(tokens) > 0 && strings.HasPrefix(tokens[0].text, "(") {
+		markers = append(markers, tokens[0])
+		tokens = tokens[1:]
+	}
+	if strings.HasPrefix(nick.text, "(") {
+		p.errorf(line, nick.offset, "expected a nickname before %s", nick.text)
+		team.players = append(team.players, input)
+		return
+	}
+
+	for _, marker := range markers {
+		for _, m := range splitMarkers(marker) {
+			switch strings.ToLower(m.text) {
+			case "(c)":
+				if team.captains > 0 {
+					p.errorf(line, m.offset, "%s already has a captain", sideTitle(side))
+				}
+				team.captains++
+				input.IsCaptain = true
+			case "(new)":
+				input.CreateNew = true
+			default:
+				p.errorf(line, m.offset, "unknown marker %s, expected (c) or (new)", m.text)
+			}
+		}
+	}
+
+	key := strings.ToLower(nick.text)
+	if other, ok := p.nicknames[key]; ok {
+		p.errorf(line, nick.offset, "%s is already listed on %s", nick.text, sideTitle(other))
+	}
+	p.nicknames[key] = side
+	nickname := nick.text
+	input.Nickname = &nickname
+
+	if len(tokens) == 0 {
+		p.errorf(line, end, "missing role of %s", nick.text)
+	} else {
+		role, ok := notationRoles[strings.ToLower(tokens[0].text)]
+		switch {
+		case !ok:
+			p.errorf(line, tokens[0].offset, "unknown role %q, expected carry, mid, offlane, pos4, pos5 or 1-5", tokens[0].text)
+		case team.roles[role]:
+			p.errorf(line, tokens[0].offset, "%s already has a %s", sideTitle(side), role)
+		default:
+			team.roles[role] = true
+			input.Role = role
+		}
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) > 0 {
+		heroStart := tokens[0].offset
+		hero := strings.TrimSpace(text[heroStart:end])
+		found, ok := heroes.Lookup(hero)
+		switch {
+		case !ok:
+			p.errorf(line, heroStart, "unknown hero %q", hero)
+		case p.picked[found.ID] != "":
+			p.errorf(line, heroStart, "%s is already picked by %s", found.LocalizedName, p.picked[found.ID])
+		default:
+			p.picked[found.ID] = nick.text
+		}
+		input.Hero = &hero
+	}
+
+	// Players with errors still count towards the team size
+	team.players = append(team.players, input)
+}
+
+// splitMarkers splits "(c)(new)" into "(c)" and "(new)"
+func splitMarkers(token notationToken) []notationToken {
+	var markers []notationToken
+	text, offset := token.text, token.offset
+	for text != "" {
+		i := strings.IndexByte(text[1:], '(')
+		if i < 0 {
+			markers = append(markers, notationToken{text: text, offset: offset})
+			break
+		}
+		markers = append(markers, notationToken{text: text[:i+1], offset: offset})
+		text, offset = text[i+1:], offset+i+1
+	}
+	return markers
+}
+
+// notationFields splits text on spaces, keeping byte offsets relative to the line
+func notationFields(text string, base int) []notationToken {
+	var tokens []notationToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				tokens = append(tokens, notationToken{text: text[start:i], offset: base + start})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, notationToken{text: text[start:], offset: base + start})
+	}
+	return tokens
+}
+
+func skipNotationSpaces(text string, start, end int) int {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	return start
+}
+
+func sideTitle(side string) string {
+	if side == "RADIANT" {
+		return "Radiant"
+	}
+	return "Dire"
+}
+
+// Statuses of the players of a game preview
+const (
+	PreviewKnown    = "known"
+	PreviewNew      = "new"
+	PreviewConflict = "conflict"
+)
+
+// PreviewPlayer shows who a nickname of the notation will be recorded as
+type PreviewPlayer struct {
+	Team      string `json:"team"`
+	Nickname  string `json:"nickname"`
+	Role      string `json:"role"`
+	IsCaptain bool   `json:"is_captain"`
+	Hero      string `json:"hero,omitempty"`
+	Status    string `json:"status"`
+	// PlayerID and Player are set for known players, Player is the recorded
+	// nickname when the notation used an alias or different case
+	PlayerID    string        `json:"player_id,omitempty"`
+	Player      string        `json:"player,omitempty"`
+	Suggestions []PlayerMatch `json:"suggestions,omitempty"`
+}
+
+// GamePreview is a parsed game that has not been recorded yet
+type GamePreview struct {
+	Request *CreateGameRequest `json:"request"`
+	Players []PreviewPlayer    `json:"players"`
+	// Ready is false while a nickname has to be fixed or marked with (new)
+	Ready bool `json:"ready"`
+}
+
+// PreviewGame resolves the nicknames of a request the way recording it would,
+// without changing anything
+func (s *PlayerService) PreviewGame(req *CreateGameRequest) (*GamePreview, error) {
+	preview := &GamePreview{Request: req, Ready: true}
+
+	teams := []struct {
+		side    string
+		players []GamePlayerInput
+	}{{"RADIANT", req.RadiantPlayers}, {"DIRE", req.DirePlayers}}
+
+	for _, team := range teams {
+		for _, input := range team.players {
+			if input.Nickname == nil {
+				return nil, errors.New("preview needs players by nickname")
+			}
+			p := PreviewPlayer{Team: team.side, Nickname: *input.Nickname, Role: input.Role, IsCaptain: input.IsCaptain}
+			if input.Hero != nil {
+				if hero, ok := heroes.Lookup(*input.Hero); ok {
+					p.Hero = hero.LocalizedName
+				}
+			}
+
+			player, err := s.ResolveNickname(*input.Nickname)
+			var conflictErr *NicknameConflictError
+			isConflict := errors.As(err, &conflictErr)
+			switch {
+			case err == nil:
+				p.Status, p.PlayerID, p.Player = PreviewKnown, player.ID, player.Nickname
+			case err == ErrPlayerNotFound || isConflict && input.CreateNew:
+				p.Status = PreviewNew
+			case isConflict:
+				p.Status, p.Suggestions = PreviewConflict, conflictErr.Conflicts[0].Suggestions
+				preview.Ready = false
+			default:
+				return nil, fmt.Errorf("failed to resolve %s: %v", *input.Nickname, err)
+			}
+			preview.Players = append(preview.Players, p)
+		}
+	}
+
+	return preview, nil
+}
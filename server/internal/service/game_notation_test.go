@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseGameNotation(t *testing.T) {
+	req, err := ParseGameNotation("R: a(c) carry Pudge, b mid, c offlane, d pos4, e pos5\nD: f 1, g 2, h(c) 3, i 4, j(new) 5 | win D")
+	if err != nil {
+		t.Fatalf("ParseGameNotation: %v", err)
+	}
+	if req.Winner != "DIRE" {
+		t.Errorf("winner = %q, want DIRE", req.Winner)
+	}
+	if len(req.RadiantPlayers) != 5 || len(req.DirePlayers) != 5 {
+		t.Fatalf("got %d Radiant and %d Dire players, want 5 each", len(req.RadiantPlayers), len(req.DirePlayers))
+	}
+	a := req.RadiantPlayers[0]
+	if *a.Nickname != "a" || a.Role != "carry" || !a.IsCaptain || a.Hero == nil || *a.Hero != "Pudge" {
+		t.Errorf("first Radiant player = %+v, want captain a, carry, Pudge", a)
+	}
+	if h := req.DirePlayers[2]; h.Role != "offlane" || !h.IsCaptain {
+		t.Errorf("third Dire player = %+v, want captain on offlane", h)
+	}
+	if j := req.DirePlayers[4]; j.Role != "pos5" || !j.CreateNew {
+		t.Errorf("fifth Dire player = %+v, want a new player on pos5", j)
+	}
+}
+
+func TestParseGameNotationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "duplicate hero and player",
+			text: "R: a(c) carry Pudge, b mid, c offlane, d pos4, e pos5\nD: f(c) 1, g 2 pudge, A 3, h 4, i 5 | win R",
+			want: []string{
+				"2:16: Pudge is already picked by a",
+				"2:23: A is already listed on Radiant",
+			},
+		},
+		{
+			name: "unknown role",
+			text: "R: a(c) carri, b mid, c offlane, d pos4, e pos5\nD: f(c) 1, g 2, h 3, i 4, j 5 | win R",
+			want: []string{
+				`1:9: unknown role "carri", expected carry, mid, offlane, pos4, pos5 or 1-5`,
+			},
+		},
+		{
+			name: "columns count runes",
+			text: "R: дан(c) керри, b mid, c offlane, d pos4, e pos5\nD: f(c) 1, g 2, h 3, i 4, j 5 | win R",
+			want: []string{
+				`1:11: unknown role "керри", expected carry, mid, offlane, pos4, pos5 or 1-5`,
+			},
+		},
+		{
+			name: "short team without a winner",
+			text: "R: a(c) carry, b mid, c offlane, d pos4\nD: f(c) 1, g 2, h 3, i 4, j 5",
+			want: []string{
+				"1:1: Radiant has 4 players, expected 5",
+				`2:30: missing winner, e.g. "win R"`,
+			},
+		},
+		{
+			name: "two captains and a repeated role",
+			text: "R: a(c) carry, b(c) mid, c mid, d pos4, e pos5\nD: f(c) 1, g 2, h 3, i 4, j 5 | win R",
+			want: []string{
+				"1:17: Radiant already has a captain",
+				"1:28: Radiant already has a mid",
+			},
+		},
+		{
+			name: "unknown side",
+			text: "R: a(c) carry, b mid, c offlane, d pos4, e pos5\nD: f(c) 1, g 2, h 3, i 4, j 5 | win X",
+			want: []string{
+				`2:37: unknown side "X", expected R or D`,
+			},
+		},
+		{
+			name: "missing team",
+			text: "R: a(c) carry, b mid, c offlane, d pos4, e pos5\nwin R",
+			want: []string{
+				"2:6: missing Dire team",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGameNotation(tt.text)
+			var notationErrors NotationErrors
+			if !errors.As(err, &notationErrors) {
+				t.Fatalf("error = %v, want NotationErrors", err)
+			}
+			got := make([]string, len(notationErrors))
+			for i, e := range notationErrors {
+				got[i] = e.String()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,27 +2,237 @@ package service
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
 
+	"ymb-cloz/internal/heroes"
+	"ymb-cloz/internal/models"
 	"ymb-cloz/internal/store"
 )
 
+var (
+	ErrGameNotFound         = errors.New("game not found")
+	ErrPendingGameNotFound  = errors.New("pending game not found")
+	ErrMatchAlreadyRecorded = errors.New("this match has already been recorded")
+	ErrPlayerListedTwice    = errors.New("a player can only play once per game")
+)
+
+// GameCreatedEvent is delivered to listeners after a new game has been committed
+type GameCreatedEvent struct {
+	Game          *models.Game
+	RatingChanges []RatingChange
+}
+
+type GameListener func(event GameCreatedEvent)
+
 type GameService interface {
-	CreateGame(req *CreateGameRequest) error
+	CreateGame(req *CreateGameRequest) (*models.Game, error)
+	Subscribe(listener GameListener)
+	UpdateGame(id string, req *CreateGameRequest) error
+	DeleteGame(id string) error
+	ListGames(filter store.GameFilter) (*GameList, error)
+	GetGame(id string) (*models.Game, error)
+	CreatePendingGame(game *models.PendingGame) error
+	ListPendingGames() ([]models.PendingGame, error)
+	DeletePendingGame(id string) error
+	ImportGamesCSV(r io.Reader, dryRun bool) (*CSVImportResult, error)
+	ExportGamesCSV(w io.Writer) error
 }
 
 type gameService struct {
-	store store.GameStore
+	store     store.GameStore
+	ratings   *RatingService
+	listeners []GameListener
 }
 
-func NewGameService(store store.GameStore) GameService {
-	return &gameService{store: store}
+func NewGameService(store store.GameStore, ratings *RatingService) GameService {
+	return &gameService{store: store, ratings: ratings}
 }
 
 type CreateGameRequest struct {
 	RadiantPlayers []GamePlayerInput `json:"radiant_players"`
 	DirePlayers    []GamePlayerInput `json:"dire_players"`
 	Winner         string            `json:"winner"`
+	// PendingGameID is removed from pending games once this game is recorded
+	PendingGameID *string `json:"pending_game_id,omitempty"`
+	// Optional times: a start with an end or a duration, or only a duration for a
+	// game that just ended, or for an edited game one counted from its recorded
+	// start. Without any of them a new game starts now and an edited game keeps
+	// its recorded times.
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	DurationMinutes *int       `json:"duration_minutes,omitempty"`
+	// MatchID is the Dota match the game was imported from
+	MatchID *int64 `json:"match_id,omitempty"`
+}
+
+const (
+	minGameDuration = time.Minute
+	maxGameDuration = 6 * time.Hour
+	// Allowed clock difference between the admin panel and the server
+	clockSkew = 5 * time.Minute
+)
+
+// Validate checks team sizes, winner, roles and captains of a game request
+func (req *CreateGameRequest) Validate() error {
+	if len(req.RadiantPlayers) != 5 || len(req.DirePlayers) != 5 {
+		return errors.New("each team must have exactly 5 players")
+	}
+
+	if req.Winner != "RADIANT" && req.Winner != "DIRE" {
+		return errors.New("winner must be either RADIANT or DIRE")
+	}
+
+	if err := validateTeam("Radiant", req.RadiantPlayers); err != nil {
+		return err
+	}
+	if err := validateTeam("Dire", req.DirePlayers); err != nil {
+		return err
+	}
+
+	all := append(append([]GamePlayerInput{}, req.RadiantPlayers...), req.DirePlayers...)
+
+	// Nicknames are matched case-insensitively, so are duplicates
+	ids := make(map[string]bool)
+	nicknames := make(map[string]bool)
+	for _, p := range all {
+		if p.ID != nil {
+			if ids[*p.ID] {
+				return fmt.Errorf("%w: %s is listed twice", ErrPlayerListedTwice, *p.ID)
+			}
+			ids[*p.ID] = true
+		}
+		if p.Nickname != nil {
+			key := strings.ToLower(*p.Nickname)
+			if nicknames[key] {
+				return fmt.Errorf("%w: %s is listed twice", ErrPlayerListedTwice, *p.Nickname)
+			}
+			nicknames[key] = true
+		}
+	}
+
+	// A hero can only be picked once per game
+	picked := make(map[int]bool)
+	for _, p := range all {
+		if p.Hero == nil {
+			continue
+		}
+		hero, _ := heroes.Lookup(*p.Hero)
+		if picked[hero.ID] {
+			return fmt.Errorf("hero %s is picked twice", hero.LocalizedName)
+		}
+		picked[hero.ID] = true
+	}
+
+	_, _, err := req.times(time.Now())
+	return err
+}
+
+// times resolves the requested start and end time, both nil if none were given
+func (req *CreateGameRequest) times(now time.Time) (*time.Time, *time.Time, error) {
+	if req.EndTime != nil && req.DurationMinutes != nil {
+		return nil, nil, errors.New("end_time and duration_minutes cannot both be provided")
+	}
+	if req.EndTime != nil && req.StartTime == nil {
+		return nil, nil, errors.New("end_time requires start_time")
+	}
+
+	start, end := req.StartTime, req.EndTime
+	if req.DurationMinutes != nil {
+		duration := time.Duration(*req.DurationMinutes) * time.Minute
+		if start == nil {
+			// Only a duration: the game has just ended
+			startTime := now.Add(-duration)
+			start, end = &startTime, &now
+		} else {
+			endTime := start.Add(duration)
+			end = &endTime
+		}
+	}
+
+	if start == nil {
+		return nil, nil, nil
+	}
+	if start.After(now.Add(clockSkew)) {
+		return nil, nil, errors.New("start_time cannot be in the future")
+	}
+	if end != nil {
+		if end.After(now.Add(clockSkew)) {
+			return nil, nil, errors.New("end_time cannot be in the future")
+		}
+		duration := end.Sub(*start)
+		if duration < minGameDuration {
+			return nil, nil, errors.New("game must end after it starts")
+		}
+		if duration > maxGameDuration {
+			return nil, nil, fmt.Errorf("game cannot last longer than %.0f hours", maxGameDuration.Hours())
+		}
+	}
+
+	return start, end, nil
+}
+
+// applyTimes copies the requested times onto a game record
+func (req *CreateGameRequest) applyTimes(game *store.Game) error {
+	start, end, err := req.times(time.Now())
+	if err != nil {
+		return err
+	}
+	if start != nil {
+		game.Timestamp = *start
+	}
+	game.EndTime = end
+	return nil
+}
+
+func validateTeam(team string, players []GamePlayerInput) error {
+	captains := 0
+	roles := make(map[string]bool)
+
+	for _, p := range players {
+		if p.ID != nil && p.Nickname != nil {
+			return errors.New("player ID and nickname cannot both be provided")
+		}
+		if p.ID == nil && p.Nickname == nil {
+			return errors.New("player ID or nickname must be provided")
+		}
+		if p.Role != "carry" && p.Role != "mid" && p.Role != "offlane" && p.Role != "pos4" && p.Role != "pos5" {
+			return errors.New("invalid role: " + p.Role)
+		}
+		if p.Hero != nil {
+			if _, ok := heroes.Lookup(*p.Hero); !ok {
+				return errors.New("unknown hero: " + *p.Hero)
+			}
+		}
+		if p.Stats != nil {
+			if err := validateStats(p.Stats); err != nil {
+				return err
+			}
+		}
+		if p.IsCaptain {
+			captains++
+		}
+		roles[p.Role] = true
+	}
+	if captains != 1 {
+		return fmt.Errorf("%s team must have exactly one captain", team)
+	}
+	if len(roles) != 5 {
+		return fmt.Errorf("%s team must have all unique roles", team)
+	}
+
+	return nil
+}
+
+type GameList struct {
+	Games  []models.Game `json:"games"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
 }
 
 type GamePlayerInput struct {
@@ -30,60 +240,220 @@ type GamePlayerInput struct {
 	ID        *string `json:"id"`
 	Role      string  `json:"role"`
 	IsCaptain bool    `json:"is_captain"`
+	// CreateNew skips the "did you mean" check for an unknown nickname
+	CreateNew bool `json:"create_new"`
+	// Hero is optional, matched against the hero catalog by name or abbreviation
+	Hero *string `json:"hero,omitempty"`
+	// Stats are optional end-of-game numbers
+	Stats *models.PerformanceStats `json:"stats,omitempty"`
 }
 
-func (s *gameService) getPlayerID(tx *sql.Tx, input GamePlayerInput) (string, error) {
+// Upper bounds that no real game gets close to, to catch typos
+const (
+	maxKills      = 100
+	maxDeaths     = 100
+	maxAssists    = 150
+	maxLastHits   = 3000
+	maxNetWorth   = 200000
+	maxHeroDamage = 500000
+)
+
+func validateStats(stats *models.PerformanceStats) error {
+	for _, c := range []struct {
+		name  string
+		value *int
+		limit int
+	}{
+		{"kills", &stats.Kills, maxKills},
+		{"deaths", &stats.Deaths, maxDeaths},
+		{"assists", &stats.Assists, maxAssists},
+		{"last_hits", stats.LastHits, maxLastHits},
+		{"net_worth", stats.NetWorth, maxNetWorth},
+		{"hero_damage", stats.HeroDamage, maxHeroDamage},
+	} {
+		if c.value != nil && (*c.value < 0 || *c.value > c.limit) {
+			return fmt.Errorf("%s must be between 0 and %d", c.name, c.limit)
+		}
+	}
+	return nil
+}
+
+// heroID returns the catalog ID of the requested hero, nil if none was given
+func (p GamePlayerInput) heroID() *int {
+	if p.Hero == nil {
+		return nil
+	}
+	hero, ok := heroes.Lookup(*p.Hero)
+	if !ok {
+		return nil
+	}
+	return &hero.ID
+}
+
+// getPlayerID resolves a roster entry to a player ID. When a new nickname is
+// suspiciously close to an existing player a conflict is returned instead.
+func (s *gameService) getPlayerID(tx *sql.Tx, input GamePlayerInput) (string, *NicknameConflict, error) {
 	// If ID is provided, verify it exists
 	if input.ID != nil {
 		exists, err := s.store.GetPlayerByIDTx(tx, *input.ID)
 		if err != nil {
-			return "", fmt.Errorf("error checking player ID: %v", err)
+			return "", nil, fmt.Errorf("error checking player ID: %v", err)
 		}
 		if !exists {
-			return "", fmt.Errorf("player with ID %s not found", *input.ID)
+			return "", nil, fmt.Errorf("player with ID %s not found", *input.ID)
 		}
-		return *input.ID, nil
+		return *input.ID, nil, nil
 	}
 
-	// If nickname is provided, get or create player
+	// If nickname is provided, resolve it or create a new player
 	if input.Nickname != nil {
-		playerID, err := s.store.GetOrCreatePlayerTx(tx, *input.Nickname)
+		playerID, err := s.store.FindPlayerByNicknameTx(tx, *input.Nickname)
+		if err == nil {
+			return playerID, nil, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", nil, fmt.Errorf("error looking up player by nickname: %v", err)
+		}
+
+		// Unknown nickname: refuse to create it if it looks like someone we already have
+		if !input.CreateNew {
+			names, err := s.store.ListPlayerNamesTx(tx)
+			if err != nil {
+				return "", nil, fmt.Errorf("error loading player names: %v", err)
+			}
+			if matches := findSimilarPlayers(names, *input.Nickname); len(matches) > 0 {
+				return "", &NicknameConflict{Nickname: *input.Nickname, Suggestions: matches}, nil
+			}
+		}
+
+		playerID, err = s.store.CreatePlayerTx(tx, *input.Nickname)
 		if err != nil {
-			return "", fmt.Errorf("error getting/creating player by nickname: %v", err)
+			return "", nil, fmt.Errorf("error creating player by nickname: %v", err)
 		}
-		return playerID, nil
+		return playerID, nil, nil
 	}
 
-	return "", fmt.Errorf("either player ID or nickname must be provided")
+	return "", nil, fmt.Errorf("either player ID or nickname must be provided")
 }
 
-func (s *gameService) CreateGame(req *CreateGameRequest) error {
-	// Create game record
-	game := &store.Game{
-		Winner: req.Winner,
-	}
+// Subscribe registers a listener for newly created games. Listeners must be
+// registered before the service starts handling requests.
+func (s *gameService) Subscribe(listener GameListener) {
+	s.listeners = append(s.listeners, listener)
+}
 
+func (s *gameService) CreateGame(req *CreateGameRequest) (*models.Game, error) {
 	// Begin transaction
 	tx, err := s.store.BeginTx()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
-	// Create game
-	err = s.store.CreateGameTx(tx, game)
+	game, players, err := s.createGameTx(tx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pending game this result belongs to is no longer pending
+	if req.PendingGameID != nil {
+		found, err := s.store.DeletePendingGameTx(tx, *req.PendingGameID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete pending game: %v", err)
+		}
+		if !found {
+			return nil, ErrPendingGameNotFound
+		}
+	}
+
+	// Update skill ratings, replaying history when the game is backdated before others
+	backdated, err := s.store.HasGamesAfterTx(tx, game)
+	if err != nil {
+		return nil, err
+	}
+	var changes []RatingChange
+	if backdated {
+		if err := s.ratings.RecomputeTx(tx); err != nil {
+			return nil, fmt.Errorf("failed to recompute ratings: %v", err)
+		}
+	} else {
+		changes, err = s.ratings.ApplyGameTx(tx, players)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update ratings: %v", err)
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	created, err := s.GetGame(game.ID)
 	if err != nil {
-		return fmt.Errorf("failed to create game: %v", err)
+		// The game is already committed, so fall back to what we know
+		log.Printf("error loading created game %s: %v", game.ID, err)
+		created = &models.Game{ID: game.ID, StartTime: game.Timestamp, EndTime: game.EndTime, Winner: game.Winner, SeasonID: game.SeasonID}
 	}
+	if backdated {
+		changes = ratingChanges(created)
+	}
+
+	for _, listener := range s.listeners {
+		go listener(GameCreatedEvent{Game: created, RatingChanges: changes})
+	}
+
+	return created, nil
+}
 
+// createGameTx inserts a game with its players, leaving ratings to the caller
+func (s *gameService) createGameTx(tx *sql.Tx, req *CreateGameRequest) (*store.Game, []store.GamePlayer, error) {
+	game := &store.Game{
+		Winner:  req.Winner,
+		MatchID: req.MatchID,
+	}
+	if err := req.applyTimes(game); err != nil {
+		return nil, nil, err
+	}
+
+	err := s.store.CreateGameTx(tx, game)
+	if err == store.ErrDuplicateMatch {
+		return nil, nil, ErrMatchAlreadyRecorded
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create game: %v", err)
+	}
+
+	// Create game players and update games_played
+	players, err := s.writeGamePlayers(tx, game, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A backdated game can land in a season that is already archived
+	if err := s.store.RefreshStandingsTx(tx, game.SeasonID); err != nil {
+		return nil, nil, fmt.Errorf("failed to refresh season standings: %v", err)
+	}
+
+	return game, players, nil
+}
+
+// writeGamePlayers resolves both rosters, inserts their game_players rows and
+// appends the game to every player's games_played
+func (s *gameService) writeGamePlayers(tx *sql.Tx, game *store.Game, req *CreateGameRequest) ([]store.GamePlayer, error) {
 	// Prepare players data
 	var players []store.GamePlayer
+	var conflicts []NicknameConflict
 
 	// Add Radiant players
 	for _, p := range req.RadiantPlayers {
-		playerID, err := s.getPlayerID(tx, p)
+		playerID, conflict, err := s.getPlayerID(tx, p)
 		if err != nil {
-			return fmt.Errorf("failed to process Radiant player: %v", err)
+			return nil, fmt.Errorf("failed to process Radiant player: %v", err)
+		}
+		if conflict != nil {
+			conflict.Team = "RADIANT"
+			conflicts = append(conflicts, *conflict)
+			continue
 		}
 
 		players = append(players, store.GamePlayer{
@@ -93,14 +463,21 @@ func (s *gameService) CreateGame(req *CreateGameRequest) error {
 			Role:      p.Role,
 			IsCaptain: p.IsCaptain,
 			IsWinner:  game.Winner == "RADIANT",
+			HeroID:    p.heroID(),
+			Stats:     p.Stats,
 		})
 	}
 
 	// Add Dire players
 	for _, p := range req.DirePlayers {
-		playerID, err := s.getPlayerID(tx, p)
+		playerID, conflict, err := s.getPlayerID(tx, p)
 		if err != nil {
-			return fmt.Errorf("failed to process Dire player: %v", err)
+			return nil, fmt.Errorf("failed to process Dire player: %v", err)
+		}
+		if conflict != nil {
+			conflict.Team = "DIRE"
+			conflicts = append(conflicts, *conflict)
+			continue
 		}
 
 		players = append(players, store.GamePlayer{
@@ -110,13 +487,28 @@ func (s *gameService) CreateGame(req *CreateGameRequest) error {
 			Role:      p.Role,
 			IsCaptain: p.IsCaptain,
 			IsWinner:  game.Winner == "DIRE",
+			HeroID:    p.heroID(),
+			Stats:     p.Stats,
 		})
 	}
 
+	if len(conflicts) > 0 {
+		return nil, &NicknameConflictError{Conflicts: conflicts}
+	}
+
+	// An ID and a nickname or alias can still resolve to the same player
+	seen := make(map[string]bool)
+	for _, p := range players {
+		if seen[p.PlayerID] {
+			return nil, fmt.Errorf("%w: player %s is listed twice", ErrPlayerListedTwice, p.PlayerID)
+		}
+		seen[p.PlayerID] = true
+	}
+
 	// Create game players
-	err = s.store.CreateGamePlayersTx(tx, game.ID, players)
+	err := s.store.CreateGamePlayersTx(tx, game.ID, players)
 	if err != nil {
-		return fmt.Errorf("failed to create game players: %v", err)
+		return nil, fmt.Errorf("failed to create game players: %v", err)
 	}
 
 	// Update games_played for all players
@@ -127,9 +519,68 @@ func (s *gameService) CreateGame(req *CreateGameRequest) error {
 
 	err = s.store.UpdatePlayersGamesTx(tx, game.ID, playerIDs)
 	if err != nil {
+		return nil, fmt.Errorf("failed to update players games count: %v", err)
+	}
+
+	return players, nil
+}
+
+func (s *gameService) UpdateGame(id string, req *CreateGameRequest) error {
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	recorded, err := s.store.LockGameTx(tx, id)
+	if err == sql.ErrNoRows {
+		return ErrGameNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock game: %v", err)
+	}
+
+	// A duration alone would mean the game just ended, an edited game keeps its start
+	times := *req
+	if times.StartTime == nil && times.DurationMinutes != nil {
+		times.StartTime = &recorded.Timestamp
+	}
+	game := &store.Game{
+		ID:     id,
+		Winner: req.Winner,
+	}
+	if err := times.applyTimes(game); err != nil {
+		return err
+	}
+
+	err = s.store.UpdateGameTx(tx, game)
+	if err != nil {
+		return fmt.Errorf("failed to update game: %v", err)
+	}
+
+	// Drop the old rosters before writing the new ones
+	if err := s.store.DeleteGamePlayersTx(tx, id); err != nil {
+		return fmt.Errorf("failed to delete game players: %v", err)
+	}
+	if err := s.store.RemoveGameFromPlayersTx(tx, id); err != nil {
 		return fmt.Errorf("failed to update players games count: %v", err)
 	}
 
+	if _, err := s.writeGamePlayers(tx, game, req); err != nil {
+		return err
+	}
+
+	// The game may have moved between seasons, keep closed seasons' standings in line
+	if err := s.store.RefreshStandingsTx(tx, recorded.SeasonID, game.SeasonID); err != nil {
+		return fmt.Errorf("failed to refresh season standings: %v", err)
+	}
+
+	// Ratings depend on game order, so replay the whole history
+	if err := s.ratings.RecomputeTx(tx); err != nil {
+		return fmt.Errorf("failed to recompute ratings: %v", err)
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
@@ -137,3 +588,203 @@ func (s *gameService) CreateGame(req *CreateGameRequest) error {
 
 	return nil
 }
+
+func (s *gameService) DeleteGame(id string) error {
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	recorded, err := s.store.LockGameTx(tx, id)
+	if err == sql.ErrNoRows {
+		return ErrGameNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock game: %v", err)
+	}
+
+	if err := s.store.DeleteGamePlayersTx(tx, id); err != nil {
+		return fmt.Errorf("failed to delete game players: %v", err)
+	}
+	if err := s.store.RemoveGameFromPlayersTx(tx, id); err != nil {
+		return fmt.Errorf("failed to update players games count: %v", err)
+	}
+	if err := s.store.DeleteGameTx(tx, id); err != nil {
+		return fmt.Errorf("failed to delete game: %v", err)
+	}
+	if err := s.store.RefreshStandingsTx(tx, recorded.SeasonID); err != nil {
+		return fmt.Errorf("failed to refresh season standings: %v", err)
+	}
+
+	// Ratings depend on game order, so replay the whole history
+	if err := s.ratings.RecomputeTx(tx); err != nil {
+		return fmt.Errorf("failed to recompute ratings: %v", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *gameService) ListGames(filter store.GameFilter) (*GameList, error) {
+	games, total, err := s.store.ListGames(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list games: %v", err)
+	}
+
+	result, err := s.withPlayers(games)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GameList{
+		Games:  result,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+func (s *gameService) GetGame(id string) (*models.Game, error) {
+	game, err := s.store.GetGame(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrGameNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game: %v", err)
+	}
+
+	result, err := s.withPlayers([]store.Game{*game})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result[0], nil
+}
+
+// withPlayers loads both rosters for the given games and converts them to the API model
+func (s *gameService) withPlayers(games []store.Game) ([]models.Game, error) {
+	result := make([]models.Game, 0, len(games))
+	if len(games) == 0 {
+		return result, nil
+	}
+
+	gameIDs := make([]string, len(games))
+	for i, g := range games {
+		gameIDs[i] = g.ID
+	}
+
+	players, err := s.store.GetGamePlayers(gameIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game players: %v", err)
+	}
+
+	byGame := make(map[string][]store.GamePlayer)
+	for _, p := range players {
+		byGame[p.GameID] = append(byGame[p.GameID], p)
+	}
+
+	for _, g := range games {
+		game := models.Game{
+			ID:          g.ID,
+			StartTime:   g.Timestamp,
+			RadiantTeam: []models.GamePlayer{},
+			DireTeam:    []models.GamePlayer{},
+			EndTime:     g.EndTime,
+			Winner:      g.Winner,
+			SeasonID:    g.SeasonID,
+			MatchID:     g.MatchID,
+		}
+
+		for _, p := range byGame[g.ID] {
+			player := models.GamePlayer{
+				GameID:       p.GameID,
+				PlayerID:     p.PlayerID,
+				Nickname:     p.Nickname,
+				Team:         p.Team,
+				Role:         models.Role(p.Role),
+				IsCaptain:    p.IsCaptain,
+				IsWinner:     p.IsWinner,
+				HeroID:       p.HeroID,
+				Stats:        p.Stats,
+				RatingBefore: p.RatingBefore,
+				RatingAfter:  p.RatingAfter,
+			}
+			if p.HeroID != nil {
+				player.Hero = heroes.LocalizedName(*p.HeroID)
+			}
+			if p.RatingBefore != nil && p.RatingAfter != nil {
+				delta := *p.RatingAfter - *p.RatingBefore
+				player.RatingDelta = &delta
+			}
+			if p.Team == "RADIANT" {
+				game.RadiantTeam = append(game.RadiantTeam, player)
+			} else {
+				game.DireTeam = append(game.DireTeam, player)
+			}
+		}
+
+		result = append(result, game)
+	}
+
+	return result, nil
+}
+
+func (s *gameService) CreatePendingGame(game *models.PendingGame) error {
+	if err := s.store.CreatePendingGame(game); err != nil {
+		return fmt.Errorf("failed to create pending game: %v", err)
+	}
+	return nil
+}
+
+func (s *gameService) ListPendingGames() ([]models.PendingGame, error) {
+	return s.store.ListPendingGames()
+}
+
+func (s *gameService) DeletePendingGame(id string) error {
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	found, err := s.store.DeletePendingGameTx(tx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete pending game: %v", err)
+	}
+	if !found {
+		return ErrPendingGameNotFound
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// ratingChanges reads the rating change of every player from a loaded game
+func ratingChanges(game *models.Game) []RatingChange {
+	var changes []RatingChange
+	for _, team := range [][]models.GamePlayer{game.RadiantTeam, game.DireTeam} {
+		for _, p := range team {
+			if p.RatingBefore == nil || p.RatingAfter == nil {
+				continue
+			}
+			changes = append(changes, RatingChange{
+				PlayerID: p.PlayerID,
+				Before:   *p.RatingBefore,
+				After:    *p.RatingAfter,
+				Delta:    *p.RatingAfter - *p.RatingBefore,
+			})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"ymb-cloz/internal/heroes"
+	"ymb-cloz/internal/models"
+	"ymb-cloz/internal/store"
+)
+
+const DefaultOpenDotaURL = "https://api.opendota.com/api"
+
+var (
+	ErrMatchNotFound    = errors.New("match not found")
+	ErrMatchUnavailable = errors.New("match data could not be fetched")
+	ErrInvalidMatch     = errors.New("match cannot be recorded")
+	ErrInvalidAccounts  = errors.New("invalid steam account links")
+)
+
+// Steam account ID the Dota API reports for players hiding their profile
+const anonymousAccountID = 4294967295
+
+type ImportMatchRequest struct {
+	MatchID int64 `json:"match_id"`
+	// Accounts links Steam account IDs to player IDs for this match, the links are
+	// kept once the match is recorded
+	Accounts map[int64]string `json:"accounts,omitempty"`
+}
+
+type UnmappedAccount struct {
+	AccountID   *int64 `json:"account_id"`
+	PersonaName string `json:"persona_name,omitempty"`
+	Team        string `json:"team"`
+	Hero        string `json:"hero,omitempty"`
+}
+
+// UnmappedAccountsError lists match players that could not be matched to our players
+type UnmappedAccountsError struct {
+	Accounts []UnmappedAccount
+}
+
+func (e *UnmappedAccountsError) Error() string {
+	parts := make([]string, 0, len(e.Accounts))
+	for _, a := range e.Accounts {
+		name := a.PersonaName
+		if a.AccountID == nil {
+			name += " (anonymous)"
+		} else {
+			name += fmt.Sprintf(" (%d)", *a.AccountID)
+		}
+		parts = append(parts, strings.TrimSpace(name))
+	}
+	return "unknown steam accounts: " + strings.Join(parts, ", ")
+}
+
+// openDotaMatch is the part of the OpenDota /matches/{id} response we use
+type openDotaMatch struct {
+	MatchID    int64            `json:"match_id"`
+	RadiantWin *bool            `json:"radiant_win"`
+	StartTime  int64            `json:"start_time"`
+	Duration   int              `json:"duration"`
+	Players    []openDotaPlayer `json:"players"`
+}
+
+type openDotaPlayer struct {
+	AccountID   *int64 `json:"account_id"`
+	PlayerSlot  int    `json:"player_slot"`
+	PersonaName string `json:"personaname"`
+	HeroID      int    `json:"hero_id"`
+	Kills       int    `json:"kills"`
+	Deaths      int    `json:"deaths"`
+	Assists     int    `json:"assists"`
+	LastHits    *int   `json:"last_hits"`
+	NetWorth    *int   `json:"net_worth"`
+	HeroDamage  *int   `json:"hero_damage"`
+	// 1 safe lane, 2 mid, 3 off lane, 4 jungle
+	LaneRole *int `json:"lane_role"`
+}
+
+func (p openDotaPlayer) isRadiant() bool {
+	return p.PlayerSlot < 128
+}
+
+func (p openDotaPlayer) netWorth() int {
+	if p.NetWorth == nil {
+		return 0
+	}
+	return *p.NetWorth
+}
+
+// MatchImporter records games from an OpenDota-compatible API
+type MatchImporter struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	players *store.PlayerStore
+	games   GameService
+}
+
+func NewMatchImporter(baseURL, apiKey string, players *store.PlayerStore, games GameService) *MatchImporter {
+	if baseURL == "" {
+		baseURL = DefaultOpenDotaURL
+	}
+	return &MatchImporter{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 15 * time.Second},
+		players: players,
+		games:   games,
+	}
+}
+
+// Import fetches a match and records it through the game service. Roles are guessed
+// from lanes and net worth and the first slot of each team is taken as its captain,
+// both can be corrected by editing the game afterwards.
+func (m *MatchImporter) Import(req *ImportMatchRequest) (*models.Game, error) {
+	if req.MatchID <= 0 {
+		return nil, fmt.Errorf("%w: match_id must be a positive number", ErrInvalidMatch)
+	}
+
+	accountIDs, err := m.checkAccounts(req.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := m.fetchMatch(req.MatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	gameReq, err := m.buildRequest(match, req.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	if err := gameReq.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMatch, err)
+	}
+
+	game, err := m.games.CreateGame(gameReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// The links are only kept once the match they were given for is recorded
+	if err := m.linkAccounts(accountIDs, req.Accounts); err != nil {
+		log.Printf("error linking steam accounts of game %s: %v", game.ID, err)
+	}
+
+	return game, nil
+}
+
+// checkAccounts validates the requested Steam account links and returns their
+// account IDs in order. Every player can have one account, and an account that
+// is already linked stays with its player.
+func (m *MatchImporter) checkAccounts(accounts map[int64]string) ([]int64, error) {
+	accountIDs := make([]int64, 0, len(accounts))
+	for accountID := range accounts {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	seen := make(map[string]int64, len(accounts))
+	for _, accountID := range accountIDs {
+		playerID := accounts[accountID]
+		if accountID <= 0 || accountID == anonymousAccountID {
+			return nil, fmt.Errorf("%w: %d is not a steam account ID", ErrInvalidAccounts, accountID)
+		}
+		if other, ok := seen[playerID]; ok {
+			return nil, fmt.Errorf("%w: accounts %d and %d are both linked to player %s", ErrInvalidAccounts, other, accountID, playerID)
+		}
+		seen[playerID] = accountID
+
+		_, err := m.players.GetPlayer(playerID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, playerID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load player: %v", err)
+		}
+	}
+
+	linked, err := m.players.GetPlayersBySteamAccounts(accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up steam accounts: %v", err)
+	}
+	for _, accountID := range accountIDs {
+		if player, ok := linked[accountID]; ok && player.ID != accounts[accountID] {
+			return nil, store.ErrDuplicateSteamAccount
+		}
+	}
+
+	return accountIDs, nil
+}
+
+// linkAccounts saves the Steam account links given with an imported match
+func (m *MatchImporter) linkAccounts(accountIDs []int64, accounts map[int64]string) error {
+	if len(accountIDs) == 0 {
+		return nil
+	}
+
+	// Begin transaction
+	tx, err := m.players.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, accountID := range accountIDs {
+		if err := m.players.SetSteamAccountTx(tx, accounts[accountID], &accountID); err != nil {
+			return err
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (m *MatchImporter) fetchMatch(matchID int64) (*openDotaMatch, error) {
+	endpoint := fmt.Sprintf("%s/matches/%d", m.baseURL, matchID)
+	if m.apiKey != "" {
+		endpoint += "?api_key=" + url.QueryEscape(m.apiKey)
+	}
+
+	resp, err := m.client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMatchUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrMatchNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %s", ErrMatchUnavailable, resp.Status)
+	}
+
+	var match openDotaMatch
+	if err := json.NewDecoder(resp.Body).Decode(&match); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrMatchUnavailable, err)
+	}
+	if match.MatchID == 0 {
+		return nil, ErrMatchNotFound
+	}
+	if match.RadiantWin == nil || len(match.Players) != 10 {
+		return nil, fmt.Errorf("%w: not a finished 5v5 game", ErrInvalidMatch)
+	}
+
+	return &match, nil
+}
+
+// buildRequest maps the match players to ours through their linked Steam
+// accounts, the accounts given with the request taking over the stored links
+func (m *MatchImporter) buildRequest(match *openDotaMatch, accounts map[int64]string) (*CreateGameRequest, error) {
+	var accountIDs []int64
+	for _, p := range match.Players {
+		if p.AccountID != nil {
+			accountIDs = append(accountIDs, *p.AccountID)
+		}
+	}
+	linked, err := m.players.GetPlayersBySteamAccounts(accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up steam accounts: %v", err)
+	}
+
+	relinked := make(map[string]bool, len(accounts))
+	for accountID, playerID := range accounts {
+		linked[accountID] = store.Player{ID: playerID}
+		relinked[playerID] = true
+	}
+	// A player given a new account no longer plays under the old one
+	for accountID, player := range linked {
+		if relinked[player.ID] && accounts[accountID] != player.ID {
+			delete(linked, accountID)
+		}
+	}
+
+	var unmapped []UnmappedAccount
+	var radiant, dire []openDotaPlayer
+	for _, p := range match.Players {
+		if p.AccountID != nil && *p.AccountID == anonymousAccountID {
+			p.AccountID = nil
+		}
+		if p.AccountID == nil || linked[*p.AccountID].ID == "" {
+			team := "DIRE"
+			if p.isRadiant() {
+				team = "RADIANT"
+			}
+			unmapped = append(unmapped, UnmappedAccount{
+				AccountID:   p.AccountID,
+				PersonaName: p.PersonaName,
+				Team:        team,
+				Hero:        heroes.LocalizedName(p.HeroID),
+			})
+			continue
+		}
+
+		if p.isRadiant() {
+			radiant = append(radiant, p)
+		} else {
+			dire = append(dire, p)
+		}
+	}
+	if len(unmapped) > 0 {
+		return nil, &UnmappedAccountsError{Accounts: unmapped}
+	}
+
+	winner := "DIRE"
+	if *match.RadiantWin {
+		winner = "RADIANT"
+	}
+	start := time.Unix(match.StartTime, 0)
+	end := start.Add(time.Duration(match.Duration) * time.Second)
+
+	return &CreateGameRequest{
+		RadiantPlayers: importTeam(radiant, linked),
+		DirePlayers:    importTeam(dire, linked),
+		Winner:         winner,
+		StartTime:      &start,
+		EndTime:        &end,
+		MatchID:        &match.MatchID,
+	}, nil
+}
+
+// importTeam converts one side of a match into roster entries
+func importTeam(team []openDotaPlayer, linked map[int64]store.Player) []GamePlayerInput {
+	sort.Slice(team, func(i, j int) bool {
+		return team[i].PlayerSlot < team[j].PlayerSlot
+	})
+	roles := guessRoles(team)
+
+	inputs := make([]GamePlayerInput, 0, len(team))
+	for i, p := range team {
+		playerID := linked[*p.AccountID].ID
+		input := GamePlayerInput{
+			ID:        &playerID,
+			Role:      string(roles[i]),
+			IsCaptain: i == 0,
+			Stats: &models.PerformanceStats{
+				Kills:      p.Kills,
+				Deaths:     p.Deaths,
+				Assists:    p.Assists,
+				LastHits:   p.LastHits,
+				NetWorth:   p.NetWorth,
+				HeroDamage: p.HeroDamage,
+			},
+		}
+		if hero, ok := heroes.ByID(p.HeroID); ok {
+			input.Hero = &hero.LocalizedName
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
+
+// guessRoles assigns mid, carry and offlane to the richest player of the matching
+// lane (or the richest one left), then pos4 and pos5 by net worth
+func guessRoles(team []openDotaPlayer) []models.Role {
+	roles := make([]models.Role, len(team))
+	assigned := make([]bool, len(team))
+
+	byNetWorth := make([]int, len(team))
+	for i := range byNetWorth {
+		byNetWorth[i] = i
+	}
+	sort.SliceStable(byNetWorth, func(a, b int) bool {
+		return team[byNetWorth[a]].netWorth() > team[byNetWorth[b]].netWorth()
+	})
+
+	pick := func(role models.Role, lane int) {
+		candidate := -1
+		for _, i := range byNetWorth {
+			if assigned[i] {
+				continue
+			}
+			if team[i].LaneRole != nil && *team[i].LaneRole == lane {
+				candidate = i
+				break
+			}
+			if candidate == -1 {
+				candidate = i
+			}
+		}
+		if candidate != -1 {
+			roles[candidate] = role
+			assigned[candidate] = true
+		}
+	}
+
+	pick(models.Mid, 2)
+	pick(models.Carry, 1)
+	pick(models.Offlane, 3)
+	for _, role := range []models.Role{models.Pos4, models.Pos5} {
+		for _, i := range byNetWorth {
+			if !assigned[i] {
+				roles[i] = role
+				assigned[i] = true
+				break
+			}
+		}
+	}
+
+	return roles
+}
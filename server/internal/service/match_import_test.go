@@ -0,0 +1,63 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"ymb-cloz/internal/models"
+)
+
+// testDotaPlayer builds a player of a parsed match, lane 0 means no lane data
+func testDotaPlayer(lane, netWorth int) openDotaPlayer {
+	p := openDotaPlayer{NetWorth: &netWorth}
+	if lane != 0 {
+		p.LaneRole = &lane
+	}
+	return p
+}
+
+func TestGuessRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		lanes []int
+		worth []int
+		want  []models.Role
+	}{
+		{
+			name:  "standard lanes",
+			lanes: []int{1, 2, 3, 3, 1},
+			worth: []int{20000, 18000, 15000, 8000, 6000},
+			want:  []models.Role{models.Carry, models.Mid, models.Offlane, models.Pos4, models.Pos5},
+		},
+		{
+			name:  "no lane data",
+			lanes: []int{0, 0, 0, 0, 0},
+			worth: []int{9000, 20000, 6000, 15000, 12000},
+			want:  []models.Role{models.Pos4, models.Mid, models.Pos5, models.Carry, models.Offlane},
+		},
+		{
+			name:  "two players in mid",
+			lanes: []int{2, 2, 1, 3, 1},
+			worth: []int{20000, 15000, 10000, 9000, 5000},
+			want:  []models.Role{models.Mid, models.Pos4, models.Carry, models.Offlane, models.Pos5},
+		},
+		{
+			name:  "nobody in the off lane",
+			lanes: []int{1, 2, 1, 4, 1},
+			worth: []int{20000, 18000, 7000, 11000, 5000},
+			want:  []models.Role{models.Carry, models.Mid, models.Pos4, models.Offlane, models.Pos5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			team := make([]openDotaPlayer, len(tt.lanes))
+			for i := range team {
+				team[i] = testDotaPlayer(tt.lanes[i], tt.worth[i])
+			}
+			if got := guessRoles(team); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("guessRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ymb-cloz/internal/store"
+)
+
+// Cyrillic letters that look like Latin ones, used to build a visual skeleton
+var confusables = map[rune]string{
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'к': "k", 'м': "m", 'н': "h",
+	'о': "o", 'р': "p", 'с': "c", 'т': "t", 'у': "y", 'х': "x", 'і': "i",
+	'ј': "j", 'ѕ': "s",
+}
+
+// Phonetic Cyrillic to Latin transliteration, so that "Даник" matches "Danik"
+var transliteration = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i",
+}
+
+type PlayerMatch struct {
+	PlayerID    string `json:"player_id"`
+	Nickname    string `json:"nickname"`
+	MatchedName string `json:"matched_name"`
+	Distance    int    `json:"distance"`
+}
+
+type NicknameConflict struct {
+	Team        string        `json:"team"`
+	Nickname    string        `json:"nickname"`
+	Suggestions []PlayerMatch `json:"suggestions"`
+}
+
+// NicknameConflictError is returned instead of auto-creating players whose
+// nicknames look like existing ones
+type NicknameConflictError struct {
+	Conflicts []NicknameConflict
+}
+
+func (e *NicknameConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		parts = append(parts, fmt.Sprintf("player %q not found, did you mean %s?", c.Nickname, c.Suggestions[0].Nickname))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// nicknameKeys returns the visual skeleton and the transliterated form of a nickname
+func nicknameKeys(nickname string) (string, string) {
+	var skeleton, translit strings.Builder
+	for _, r := range strings.ToLower(nickname) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if v, ok := confusables[r]; ok {
+			skeleton.WriteString(v)
+		} else {
+			skeleton.WriteRune(r)
+		}
+		if v, ok := transliteration[r]; ok {
+			translit.WriteString(v)
+		} else {
+			translit.WriteRune(r)
+		}
+	}
+	return skeleton.String(), translit.String()
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// maxDistance is the largest edit distance still considered a likely typo
+func maxDistance(key string) int {
+	if len([]rune(key)) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// findSimilarPlayers returns players whose nickname or alias is confusably close
+// to the given one, best matches first
+func findSimilarPlayers(names []store.PlayerName, nickname string) []PlayerMatch {
+	skeleton, translit := nicknameKeys(nickname)
+	if translit == "" {
+		return nil
+	}
+
+	nicknames := make(map[string]string)
+	for _, n := range names {
+		if !n.IsAlias {
+			nicknames[n.PlayerID] = n.Name
+		}
+	}
+
+	best := make(map[string]PlayerMatch)
+	for _, n := range names {
+		otherSkeleton, otherTranslit := nicknameKeys(n.Name)
+		distance := min(levenshtein(skeleton, otherSkeleton), levenshtein(translit, otherTranslit))
+		if distance > maxDistance(translit) {
+			continue
+		}
+
+		if current, ok := best[n.PlayerID]; ok && current.Distance <= distance {
+			continue
+		}
+		best[n.PlayerID] = PlayerMatch{
+			PlayerID:    n.PlayerID,
+			Nickname:    nicknames[n.PlayerID],
+			MatchedName: n.Name,
+			Distance:    distance,
+		}
+	}
+
+	matches := make([]PlayerMatch, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Nickname < matches[j].Nickname
+	})
+
+	if len(matches) > 3 {
+		matches = matches[:3]
+	}
+	return matches
+}
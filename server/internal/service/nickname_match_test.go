@@ -0,0 +1,74 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"ymb-cloz/internal/store"
+)
+
+func TestFindSimilarPlayers(t *testing.T) {
+	names := []store.PlayerName{
+		{PlayerID: "p1", Name: "Danik"},
+		{PlayerID: "p2", Name: "Miracle"},
+		{PlayerID: "p2", Name: "Mira", IsAlias: true},
+		{PlayerID: "p3", Name: "Dank"},
+	}
+
+	tests := []struct {
+		name     string
+		nickname string
+		want     []PlayerMatch
+	}{
+		{
+			name:     "transliterated cyrillic",
+			nickname: "Даник",
+			want: []PlayerMatch{
+				{PlayerID: "p1", Nickname: "Danik", MatchedName: "Danik", Distance: 0},
+				{PlayerID: "p3", Nickname: "Dank", MatchedName: "Dank", Distance: 1},
+			},
+		},
+		{
+			name:     "cyrillic look-alike letter",
+			nickname: "dаnik",
+			want: []PlayerMatch{
+				{PlayerID: "p1", Nickname: "Danik", MatchedName: "Danik", Distance: 0},
+				{PlayerID: "p3", Nickname: "Dank", MatchedName: "Dank", Distance: 1},
+			},
+		},
+		{
+			name:     "typo in nickname",
+			nickname: "Miracl",
+			want: []PlayerMatch{
+				{PlayerID: "p2", Nickname: "Miracle", MatchedName: "Miracle", Distance: 1},
+			},
+		},
+		{
+			name:     "typo in alias",
+			nickname: "mirra",
+			want: []PlayerMatch{
+				{PlayerID: "p2", Nickname: "Miracle", MatchedName: "Mira", Distance: 1},
+			},
+		},
+		{
+			name:     "no similar players",
+			nickname: "zzz",
+		},
+		{
+			name:     "no letters",
+			nickname: "!!!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findSimilarPlayers(names, tt.nickname)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findSimilarPlayers(%q) = %+v, want %+v", tt.nickname, got, tt.want)
+			}
+		})
+	}
+}
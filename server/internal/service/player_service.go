@@ -1,35 +1,240 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
 	"ymb-cloz/internal/store"
 )
 
+const (
+	LeaderboardWinRate  = "winrate"
+	LeaderboardGames    = "games"
+	LeaderboardCaptains = "captains"
+	LeaderboardRole     = "role"
+)
+
+var (
+	ErrUnknownLeaderboard = errors.New("unknown leaderboard, expected winrate, games, captains or role")
+	ErrRoleRequired       = errors.New("role leaderboard requires a role")
+	ErrPlayerNotFound     = errors.New("player not found")
+	ErrMergeConflict      = errors.New("players appear in the same game and cannot be merged")
+)
+
+type UpdatePlayerRequest struct {
+	Nickname *string `json:"nickname"`
+	IsActive *bool   `json:"is_active"`
+	// SteamAccountID links the player for match imports, 0 removes the link
+	SteamAccountID *int64 `json:"steam_account_id"`
+}
+
+type AliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+type MergePlayersRequest struct {
+	SourceID string `json:"source_id"`
+}
+
 type PlayerService struct {
-	store *store.PlayerStore
+	store   *store.PlayerStore
+	ratings *RatingService
 }
 
-func NewPlayerService(store *store.PlayerStore) *PlayerService {
-	return &PlayerService{store: store}
+func NewPlayerService(store *store.PlayerStore, ratings *RatingService) *PlayerService {
+	return &PlayerService{store: store, ratings: ratings}
 }
 
 func (s *PlayerService) GetAllPlayers() ([]store.Player, error) {
 	return s.store.GetAllPlayers()
 }
 
-func (s *PlayerService) GetTopByWinRate() ([]store.PlayerStats, error) {
-	return s.store.GetTopByWinRate()
+// GetLeaderboard returns structured player statistics for one of the leaderboard kinds
+func (s *PlayerService) GetLeaderboard(kind string, filter store.LeaderboardFilter) ([]store.PlayerStats, error) {
+	switch kind {
+	case LeaderboardWinRate:
+		return s.store.GetLeaderboard(store.SortByWinRate, filter)
+	case LeaderboardGames:
+		return s.store.GetLeaderboard(store.SortByGames, filter)
+	case LeaderboardCaptains:
+		filter.CaptainOnly = true
+		return s.store.GetLeaderboard(store.SortByWinRate, filter)
+	case LeaderboardRole:
+		if filter.Role == "" {
+			return nil, ErrRoleRequired
+		}
+		return s.store.GetLeaderboard(store.SortByWinRate, filter)
+	default:
+		return nil, ErrUnknownLeaderboard
+	}
+}
+
+func (s *PlayerService) GetPlayer(id string) (*store.Player, error) {
+	player, err := s.store.GetPlayer(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlayerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player: %v", err)
+	}
+	return player, nil
+}
+
+func (s *PlayerService) UpdatePlayer(id string, req *UpdatePlayerRequest) (*store.Player, error) {
+	if _, err := s.GetPlayer(id); err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if req.Nickname != nil {
+		if err := s.store.RenamePlayerTx(tx, id, strings.TrimSpace(*req.Nickname)); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.IsActive != nil {
+		if err := s.store.SetPlayerActiveTx(tx, id, *req.IsActive); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.SteamAccountID != nil {
+		accountID := req.SteamAccountID
+		if *accountID == 0 {
+			accountID = nil
+		}
+		if err := s.store.SetSteamAccountTx(tx, id, accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return s.GetPlayer(id)
+}
+
+// MergePlayers moves all games of the source player to the target player and deletes the source
+func (s *PlayerService) MergePlayers(targetID, sourceID string) (*store.Player, error) {
+	if _, err := s.GetPlayer(targetID); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetPlayer(sourceID); err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	shared, err := s.store.CountSharedGamesTx(tx, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if shared > 0 {
+		return nil, ErrMergeConflict
+	}
+
+	if err := s.store.ReassignGamePlayersTx(tx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	// Rebuilding from game_players merges both histories without double-counting
+	if err := s.store.RebuildGamesPlayedTx(tx, targetID); err != nil {
+		return nil, err
+	}
+
+	// Keep the old nickname resolvable for future games
+	if err := s.store.MoveAliasesTx(tx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+	if err := s.store.MoveSteamAccountTx(tx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.DeletePlayerTx(tx, sourceID); err != nil {
+		return nil, err
+	}
+
+	// The merged history changes the target's results, so replay ratings
+	if err := s.ratings.RecomputeTx(tx); err != nil {
+		return nil, fmt.Errorf("failed to recompute ratings: %v", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return s.GetPlayer(targetID)
+}
+
+func (s *PlayerService) GetAliases(playerID string) ([]string, error) {
+	if _, err := s.GetPlayer(playerID); err != nil {
+		return nil, err
+	}
+	return s.store.GetAliases(playerID)
 }
 
-func (s *PlayerService) GetTopByGames() ([]store.PlayerStats, error) {
-	return s.store.GetTopByGames()
+func (s *PlayerService) AddAlias(playerID, alias string) error {
+	if _, err := s.GetPlayer(playerID); err != nil {
+		return err
+	}
+	return s.store.AddAlias(playerID, strings.TrimSpace(alias))
 }
 
-func (s *PlayerService) GetTopCaptains() ([]store.PlayerStats, error) {
-	return s.store.GetTopCaptains()
+func (s *PlayerService) DeleteAlias(playerID, alias string) (bool, error) {
+	return s.store.DeleteAlias(playerID, alias)
+}
+
+// ResolveNickname finds a player by exact nickname or alias, ignoring case. If
+// nothing matches, a NicknameConflictError with suggestions or ErrPlayerNotFound is returned.
+func (s *PlayerService) ResolveNickname(nickname string) (*store.Player, error) {
+	names, err := s.store.ListPlayerNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var matchID string
+	for _, n := range names {
+		if strings.EqualFold(n.Name, nickname) {
+			if matchID != "" && matchID != n.PlayerID {
+				matchID = ""
+				break
+			}
+			matchID = n.PlayerID
+		}
+	}
+	if matchID != "" {
+		return s.GetPlayer(matchID)
+	}
+
+	if matches := findSimilarPlayers(names, nickname); len(matches) > 0 {
+		return nil, &NicknameConflictError{Conflicts: []NicknameConflict{{Nickname: nickname, Suggestions: matches}}}
+	}
+	return nil, ErrPlayerNotFound
 }
 
-func (s *PlayerService) GetTopByRole(role string) ([]store.PlayerStats, error) {
-	return s.store.GetTopByRole(role)
+// GetPlayerStats returns the overall record of a player, empty if they have no games yet
+func (s *PlayerService) GetPlayerStats(player *store.Player) (store.PlayerStats, error) {
+	stats, err := s.store.GetPlayerStats(player.ID)
+	if err == sql.ErrNoRows {
+		return store.PlayerStats{ID: player.ID, Nickname: player.Nickname}, nil
+	}
+	return stats, err
 }
 
 // test
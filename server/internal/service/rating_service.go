@@ -0,0 +1,309 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"ymb-cloz/internal/store"
+)
+
+// Glicko-2 parameters, see http://www.glicko.net/glicko/glicko2.pdf
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	glickoScale       = 173.7178
+	glickoTau         = 0.5
+	glickoConvergence = 0.000001
+)
+
+type RatingChange struct {
+	PlayerID string  `json:"player_id"`
+	Before   float64 `json:"before"`
+	After    float64 `json:"after"`
+	Delta    float64 `json:"delta"`
+}
+
+type RatingService struct {
+	store *store.RatingStore
+}
+
+func NewRatingService(store *store.RatingStore) *RatingService {
+	return &RatingService{store: store}
+}
+
+func newRating(playerID string) store.Rating {
+	return store.Rating{
+		PlayerID:   playerID,
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// GetRating returns nil if the player has not been rated yet
+func (s *RatingService) GetRating(playerID string) (*store.Rating, error) {
+	ratings, err := s.store.GetRatings([]string{playerID})
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := ratings[playerID]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+// GetTopRatings ranks players by current rating, or by their rating at the end
+// of a season when seasonID is set
+func (s *RatingService) GetTopRatings(seasonID string, minGames, limit int) ([]store.Rating, error) {
+	return s.store.GetTopRatings(seasonID, minGames, limit)
+}
+
+// ApplyGameTx updates the ratings of everyone in a freshly recorded game
+func (s *RatingService) ApplyGameTx(tx *sql.Tx, players []store.GamePlayer) ([]RatingChange, error) {
+	if err := s.store.LockRatingsTx(tx); err != nil {
+		return nil, err
+	}
+
+	playerIDs := make([]string, len(players))
+	for i, p := range players {
+		playerIDs[i] = p.PlayerID
+	}
+
+	ratings, err := s.store.GetRatingsTx(tx, playerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := rateGame(ratings, players)
+	if err := s.saveChangesTx(tx, players[0].GameID, changes); err != nil {
+		return nil, err
+	}
+
+	updated := make([]store.Rating, 0, len(players))
+	for _, p := range players {
+		updated = append(updated, ratings[p.PlayerID])
+	}
+	if err := s.store.SaveRatingsTx(tx, updated); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// RecomputeTx rebuilds every rating by replaying all games in chronological order
+func (s *RatingService) RecomputeTx(tx *sql.Tx) error {
+	if err := s.store.LockRatingsTx(tx); err != nil {
+		return err
+	}
+
+	history, err := s.store.GetGameHistoryTx(tx)
+	if err != nil {
+		return err
+	}
+
+	ratings := make(map[string]store.Rating)
+	for _, game := range groupByGame(history) {
+		changes := rateGame(ratings, game)
+		if err := s.saveChangesTx(tx, game[0].GameID, changes); err != nil {
+			return err
+		}
+	}
+
+	if err := s.store.DeleteAllRatingsTx(tx); err != nil {
+		return err
+	}
+
+	all := make([]store.Rating, 0, len(ratings))
+	for _, r := range ratings {
+		all = append(all, r)
+	}
+	return s.store.SaveRatingsTx(tx, all)
+}
+
+func (s *RatingService) Recompute() error {
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.RecomputeTx(tx); err != nil {
+		return fmt.Errorf("failed to recompute ratings: %v", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *RatingService) saveChangesTx(tx *sql.Tx, gameID string, changes []RatingChange) error {
+	for _, c := range changes {
+		if err := s.store.SaveGameRatingsTx(tx, gameID, c.PlayerID, c.Before, c.After); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RatingService) GetRatingHistory(playerID string) ([]store.RatingHistoryEntry, error) {
+	return s.store.GetRatingHistory(playerID)
+}
+
+// EnsureComputed replays the game history when no ratings have been stored yet,
+// e.g. right after the ratings table was introduced on an existing database
+func (s *RatingService) EnsureComputed() error {
+	empty, err := s.store.IsEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return nil
+	}
+	return s.Recompute()
+}
+
+// groupByGame splits chronologically ordered game_players rows into games
+func groupByGame(history []store.GamePlayer) [][]store.GamePlayer {
+	var games [][]store.GamePlayer
+	for i, p := range history {
+		if i == 0 || history[i-1].GameID != p.GameID {
+			games = append(games, nil)
+		}
+		games[len(games)-1] = append(games[len(games)-1], p)
+	}
+	return games
+}
+
+// rateGame updates ratings in place for one game and returns each player's change.
+// Every player is rated against the opposing team's average, shifted by how much
+// stronger or weaker their own team is than them, so winning with a strong stack
+// earns less than winning with a weak one.
+func rateGame(ratings map[string]store.Rating, players []store.GamePlayer) []RatingChange {
+	before := make(map[string]store.Rating, len(players))
+	for _, p := range players {
+		r, ok := ratings[p.PlayerID]
+		if !ok {
+			r = newRating(p.PlayerID)
+		}
+		before[p.PlayerID] = r
+	}
+
+	type teamStats struct {
+		rating    float64
+		deviation float64
+	}
+	teams := make(map[string]teamStats)
+	counts := make(map[string]int)
+	for _, p := range players {
+		r := before[p.PlayerID]
+		t := teams[p.Team]
+		t.rating += r.Rating
+		t.deviation += r.Deviation * r.Deviation
+		teams[p.Team] = t
+		counts[p.Team]++
+	}
+	for team, t := range teams {
+		n := float64(counts[team])
+		teams[team] = teamStats{rating: t.rating / n, deviation: math.Sqrt(t.deviation / n)}
+	}
+
+	changes := make([]RatingChange, 0, len(players))
+	for _, p := range players {
+		own := teams[p.Team]
+		var opponent teamStats
+		for team, t := range teams {
+			if team != p.Team {
+				opponent = t
+			}
+		}
+
+		r := before[p.PlayerID]
+		score := 0.0
+		if p.IsWinner {
+			score = 1.0
+		}
+
+		updated := glickoUpdate(r, opponent.rating-own.rating+r.Rating, opponent.deviation, score)
+		ratings[p.PlayerID] = updated
+
+		changes = append(changes, RatingChange{
+			PlayerID: p.PlayerID,
+			Before:   r.Rating,
+			After:    updated.Rating,
+			Delta:    updated.Rating - r.Rating,
+		})
+	}
+
+	return changes
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// glickoUpdate applies a single-game Glicko-2 rating period
+func glickoUpdate(r store.Rating, opponentRating, opponentDeviation, score float64) store.Rating {
+	mu := (r.Rating - DefaultRating) / glickoScale
+	phi := r.Deviation / glickoScale
+	muJ := (opponentRating - DefaultRating) / glickoScale
+	phiJ := opponentDeviation / glickoScale
+
+	g := glickoG(phiJ)
+	e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+	v := 1 / (g * g * e * (1 - e))
+	delta := v * g * (score - e)
+
+	// Determine new volatility with the Illinois algorithm
+	a := math.Log(r.Volatility * r.Volatility)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		num := ex * (delta*delta - phi*phi - v - ex)
+		den := 2 * math.Pow(phi*phi+v+ex, 2)
+		return num/den - (x-a)/(glickoTau*glickoTau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoConvergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	volatility := math.Exp(A / 2)
+
+	phiStar := math.Sqrt(phi*phi + volatility*volatility)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*g*(score-e)
+
+	return store.Rating{
+		PlayerID:   r.PlayerID,
+		Nickname:   r.Nickname,
+		Rating:     newMu*glickoScale + DefaultRating,
+		Deviation:  math.Min(newPhi*glickoScale, DefaultDeviation),
+		Volatility: volatility,
+		Games:      r.Games + 1,
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"ymb-cloz/internal/store"
+)
+
+// Expected values follow Glickman's example (http://www.glicko.net/glicko/glicko2.pdf):
+// a 1500/200/0.06 player, tau 0.5, here playing one opponent per rating period.
+// With all three opponents in one period the paper gets 1464.06/151.52/0.05999.
+func TestGlickoUpdate(t *testing.T) {
+	tests := []struct {
+		name              string
+		player            store.Rating
+		opponentRating    float64
+		opponentDeviation float64
+		score             float64
+		rating            float64
+		deviation         float64
+		volatility        float64
+	}{
+		{
+			name:              "win against a weaker opponent",
+			player:            store.Rating{Rating: 1500, Deviation: 200, Volatility: 0.06},
+			opponentRating:    1400,
+			opponentDeviation: 30,
+			score:             1,
+			rating:            1563.56,
+			deviation:         175.40,
+			volatility:        0.059999,
+		},
+		{
+			name:              "loss against a stronger opponent",
+			player:            store.Rating{Rating: 1500, Deviation: 200, Volatility: 0.06},
+			opponentRating:    1700,
+			opponentDeviation: 300,
+			score:             0,
+			rating:            1455.86,
+			deviation:         186.98,
+			volatility:        0.059999,
+		},
+		{
+			name:              "first game of two new players",
+			player:            store.Rating{Rating: DefaultRating, Deviation: DefaultDeviation, Volatility: DefaultVolatility},
+			opponentRating:    DefaultRating,
+			opponentDeviation: DefaultDeviation,
+			score:             1,
+			rating:            1662.31,
+			deviation:         290.32,
+			volatility:        0.060000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := glickoUpdate(tt.player, tt.opponentRating, tt.opponentDeviation, tt.score)
+			if math.Abs(got.Rating-tt.rating) > 0.01 {
+				t.Errorf("rating = %.4f, want %.2f", got.Rating, tt.rating)
+			}
+			if math.Abs(got.Deviation-tt.deviation) > 0.01 {
+				t.Errorf("deviation = %.4f, want %.2f", got.Deviation, tt.deviation)
+			}
+			if math.Abs(got.Volatility-tt.volatility) > 0.000001 {
+				t.Errorf("volatility = %.7f, want %.6f", got.Volatility, tt.volatility)
+			}
+			if got.Games != tt.player.Games+1 {
+				t.Errorf("games = %d, want %d", got.Games, tt.player.Games+1)
+			}
+		})
+	}
+}
+
+func TestRateGame(t *testing.T) {
+	var players []store.GamePlayer
+	for i := 0; i < 10; i++ {
+		team := "RADIANT"
+		if i >= 5 {
+			team = "DIRE"
+		}
+		players = append(players, store.GamePlayer{
+			GameID:   "game",
+			PlayerID: fmt.Sprintf("p%d", i),
+			Team:     team,
+			IsWinner: team == "RADIANT",
+		})
+	}
+
+	tests := []struct {
+		name    string
+		ratings map[string]store.Rating
+		// Expected deltas of the first Radiant and first Dire player
+		winner, loser float64
+	}{
+		{
+			name:    "new players",
+			ratings: map[string]store.Rating{},
+			winner:  162.31,
+			loser:   -162.31,
+		},
+		{
+			name: "winning with a stack earns less",
+			ratings: map[string]store.Rating{
+				"p0": {PlayerID: "p0", Rating: 1500, Deviation: DefaultDeviation, Volatility: DefaultVolatility},
+				"p1": {PlayerID: "p1", Rating: 1900, Deviation: DefaultDeviation, Volatility: DefaultVolatility},
+				"p2": {PlayerID: "p2", Rating: 1900, Deviation: DefaultDeviation, Volatility: DefaultVolatility},
+			},
+			// p0 is rated against 1500 - 1660 + 1500 = 1340
+			winner: glickoUpdate(newRating("p0"), 1340, DefaultDeviation, 1).Rating - DefaultRating,
+			loser:  glickoUpdate(newRating("p5"), 1660, math.Sqrt((3*350*350+2*350*350)/5), 0).Rating - DefaultRating,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := rateGame(tt.ratings, players)
+			if len(changes) != len(players) {
+				t.Fatalf("got %d changes, want %d", len(changes), len(players))
+			}
+
+			byPlayer := make(map[string]RatingChange)
+			for _, c := range changes {
+				byPlayer[c.PlayerID] = c
+				if got := tt.ratings[c.PlayerID].Rating; got != c.After {
+					t.Errorf("stored rating of %s = %.2f, want %.2f", c.PlayerID, got, c.After)
+				}
+			}
+			if got := byPlayer["p0"].Delta; math.Abs(got-tt.winner) > 0.01 {
+				t.Errorf("winner delta = %.2f, want %.2f", got, tt.winner)
+			}
+			if got := byPlayer["p5"].Delta; math.Abs(got-tt.loser) > 0.01 {
+				t.Errorf("loser delta = %.2f, want %.2f", got, tt.loser)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ymb-cloz/internal/store"
+)
+
+var (
+	ErrSeasonNotFound = errors.New("season not found")
+	ErrSeasonOpen     = errors.New("season is still in progress")
+)
+
+// SeasonAll selects the whole history instead of a single season
+const SeasonAll = "all"
+
+type CloseSeasonRequest struct {
+	// Name of the season that starts once the current one is closed
+	NextName string `json:"next_name"`
+}
+
+type ClosedSeason struct {
+	Closed    *store.Season          `json:"closed"`
+	Next      *store.Season          `json:"next"`
+	Standings []store.SeasonStanding `json:"standings"`
+}
+
+type SeasonService struct {
+	store *store.SeasonStore
+}
+
+func NewSeasonService(store *store.SeasonStore) *SeasonService {
+	return &SeasonService{store: store}
+}
+
+func (s *SeasonService) ListSeasons() ([]store.Season, error) {
+	return s.store.ListSeasons()
+}
+
+func (s *SeasonService) GetCurrentSeason() (*store.Season, error) {
+	season, err := s.store.GetActiveSeason()
+	if err == sql.ErrNoRows {
+		return nil, ErrSeasonNotFound
+	}
+	return season, err
+}
+
+// ResolveSeason turns a season reference into a season: empty or "current" is the
+// active season, "all" is nil for the whole history, otherwise a season number or a
+// UUID that the caller has already validated
+func (s *SeasonService) ResolveSeason(ref string) (*store.Season, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+
+	var season *store.Season
+	var err error
+	switch {
+	case ref == SeasonAll:
+		return nil, nil
+	case ref == "" || ref == "current":
+		season, err = s.store.GetActiveSeason()
+	default:
+		if number, convErr := strconv.Atoi(ref); convErr == nil {
+			season, err = s.store.GetSeasonByNumber(number)
+		} else {
+			season, err = s.store.GetSeason(ref)
+		}
+	}
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSeasonNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get season: %v", err)
+	}
+	return season, nil
+}
+
+// CloseSeason ends the active season, archives its final standings and starts the next one
+func (s *SeasonService) CloseSeason(req *CloseSeasonRequest) (*ClosedSeason, error) {
+	// Begin transaction
+	tx, err := s.store.BeginTx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	current, err := s.store.LockActiveSeasonTx(tx)
+	if err == sql.ErrNoRows {
+		return nil, ErrSeasonNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock season: %v", err)
+	}
+
+	if err := s.store.ArchiveStandingsTx(tx, current.ID); err != nil {
+		return nil, err
+	}
+	if err := s.store.EndSeasonTx(tx, current); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.NextName)
+	if name == "" {
+		name = fmt.Sprintf("Season %d", current.Number+1)
+	}
+	next, err := s.store.CreateSeasonTx(tx, current.Number+1, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	standings, err := s.store.GetStandings(current.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get standings: %v", err)
+	}
+
+	return &ClosedSeason{Closed: current, Next: next, Standings: standings}, nil
+}
+
+// GetStandings returns the archived final standings of a closed season
+func (s *SeasonService) GetStandings(seasonID string) (*store.Season, []store.SeasonStanding, error) {
+	season, err := s.store.GetSeason(seasonID)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrSeasonNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get season: %v", err)
+	}
+	if season.EndedAt == nil {
+		return nil, nil, ErrSeasonOpen
+	}
+
+	standings, err := s.store.GetStandings(seasonID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get standings: %v", err)
+	}
+	return season, standings, nil
+}
@@ -0,0 +1,504 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+
+	"ymb-cloz/internal/heroes"
+	"ymb-cloz/internal/store"
+)
+
+type StatsService struct {
+	store *store.StatsStore
+}
+
+func NewStatsService(store *store.StatsStore) *StatsService {
+	return &StatsService{store: store}
+}
+
+type DuoReport struct {
+	Best  []store.DuoStats `json:"best"`
+	Worst []store.DuoStats `json:"worst"`
+}
+
+// GetDuos returns the best and worst pairs by win rate, optionally for one player
+// or one season only
+func (s *StatsService) GetDuos(playerID, seasonID string, minGames, limit int) (*DuoReport, error) {
+	duos, err := s.store.GetDuoStats(playerID, seasonID, minGames)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DuoReport{
+		Best:  duos[:min(limit, len(duos))],
+		Worst: []store.DuoStats{},
+	}
+	for i := len(duos) - 1; i >= 0 && len(report.Worst) < limit; i-- {
+		report.Worst = append(report.Worst, duos[i])
+	}
+
+	return report, nil
+}
+
+func (s *StatsService) GetHeadToHead(playerA, playerB string) (*store.HeadToHead, error) {
+	h2h, err := s.store.GetHeadToHead(playerA, playerB)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlayerNotFound
+	}
+	return h2h, err
+}
+
+type Rivals struct {
+	// Nemesis is the opponent the player lost to most often
+	Nemesis *store.OpponentStats `json:"nemesis"`
+	// FavouriteVictim is the opponent the player beat most often
+	FavouriteVictim *store.OpponentStats `json:"favourite_victim"`
+}
+
+func (s *StatsService) GetRivals(playerID string) (*Rivals, error) {
+	opponents, err := s.store.GetOpponentStats(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rivals := &Rivals{}
+	for i := range opponents {
+		o := &opponents[i]
+		// Ties go to the more lopsided record
+		if o.Losses > 0 && (rivals.Nemesis == nil || o.Losses > rivals.Nemesis.Losses ||
+			(o.Losses == rivals.Nemesis.Losses && o.WinRate < rivals.Nemesis.WinRate)) {
+			rivals.Nemesis = o
+		}
+		if o.Wins > 0 && (rivals.FavouriteVictim == nil || o.Wins > rivals.FavouriteVictim.Wins ||
+			(o.Wins == rivals.FavouriteVictim.Wins && o.WinRate > rivals.FavouriteVictim.WinRate)) {
+			rivals.FavouriteVictim = o
+		}
+	}
+
+	return rivals, nil
+}
+
+// Streaks of this length are worth announcing in chat
+const NotableStreak = 3
+
+type Streaks struct {
+	PlayerID    string `json:"player_id"`
+	Nickname    string `json:"nickname"`
+	CurrentType string `json:"current_type"` // "win", "loss" or "" without games
+	Current     int    `json:"current"`
+	LongestWin  int    `json:"longest_win"`
+	LongestLoss int    `json:"longest_loss"`
+}
+
+// computeStreaks walks chronologically ordered results of one player
+func computeStreaks(results []store.PlayerResult) Streaks {
+	var s Streaks
+	for _, r := range results {
+		resultType := "loss"
+		if r.IsWinner {
+			resultType = "win"
+		}
+
+		if resultType == s.CurrentType {
+			s.Current++
+		} else {
+			s.CurrentType = resultType
+			s.Current = 1
+		}
+
+		if r.IsWinner {
+			s.LongestWin = max(s.LongestWin, s.Current)
+		} else {
+			s.LongestLoss = max(s.LongestLoss, s.Current)
+		}
+	}
+	return s
+}
+
+func groupResultsByPlayer(results []store.PlayerResult) ([]string, map[string][]store.PlayerResult) {
+	var order []string
+	byPlayer := make(map[string][]store.PlayerResult)
+	for _, r := range results {
+		if _, ok := byPlayer[r.PlayerID]; !ok {
+			order = append(order, r.PlayerID)
+		}
+		byPlayer[r.PlayerID] = append(byPlayer[r.PlayerID], r)
+	}
+	return order, byPlayer
+}
+
+// GetStreaks returns streaks of the given players, or of every active player
+// when none are given, longest current streak first. An empty seasonID covers
+// the whole history.
+func (s *StatsService) GetStreaks(seasonID string, playerIDs ...string) ([]Streaks, error) {
+	results, err := s.store.GetPlayerResults(seasonID, playerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	order, byPlayer := groupResultsByPlayer(results)
+	streaks := make([]Streaks, 0, len(order))
+	for _, id := range order {
+		streak := computeStreaks(byPlayer[id])
+		streak.PlayerID = id
+		streak.Nickname = byPlayer[id][0].Nickname
+		streaks = append(streaks, streak)
+	}
+
+	sort.SliceStable(streaks, func(i, j int) bool {
+		return streaks[i].Current > streaks[j].Current
+	})
+	return streaks, nil
+}
+
+type StreakEvent struct {
+	PlayerID string `json:"player_id"`
+	Nickname string `json:"nickname"`
+	// Started is true when the game took a streak to NotableStreak, false when it ended one
+	Started bool   `json:"started"`
+	Type    string `json:"type"`
+	Length  int    `json:"length"`
+}
+
+// GetStreakEvents reports notable streaks that were started or broken by the given game
+func (s *StatsService) GetStreakEvents(gameID string, playerIDs []string) ([]StreakEvent, error) {
+	results, err := s.store.GetPlayerResults("", playerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, byPlayer := groupResultsByPlayer(results)
+	var events []StreakEvent
+	for _, id := range playerIDs {
+		history := byPlayer[id]
+		// Only announce if this game is the player's latest one
+		if len(history) == 0 || history[len(history)-1].GameID != gameID {
+			continue
+		}
+
+		after := computeStreaks(history)
+		before := computeStreaks(history[:len(history)-1])
+		nickname := history[0].Nickname
+
+		if after.Current == NotableStreak {
+			events = append(events, StreakEvent{PlayerID: id, Nickname: nickname, Started: true, Type: after.CurrentType, Length: after.Current})
+		}
+		if before.CurrentType != after.CurrentType && before.Current >= NotableStreak {
+			events = append(events, StreakEvent{PlayerID: id, Nickname: nickname, Started: false, Type: before.CurrentType, Length: before.Current})
+		}
+	}
+
+	return events, nil
+}
+
+// Games shorter than this many minutes count as short by default
+const DefaultShortGameMinutes = 35
+
+type DurationReport struct {
+	SplitMinutes int                   `json:"split_minutes"`
+	Players      []PlayerDurationStats `json:"players"`
+}
+
+type PlayerDurationStats struct {
+	store.DurationStats
+	ShortWinRate float64 `json:"short_win_rate"`
+	LongWinRate  float64 `json:"long_win_rate"`
+}
+
+// GetDurationStats returns average game length and short vs long game win rates,
+// for one player or every active player, in one season or all time when seasonID is empty
+func (s *StatsService) GetDurationStats(playerID, seasonID string, splitMinutes int) (*DurationReport, error) {
+	stats, err := s.store.GetDurationStats(playerID, seasonID, splitMinutes)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DurationReport{SplitMinutes: splitMinutes, Players: make([]PlayerDurationStats, 0, len(stats))}
+	for _, d := range stats {
+		report.Players = append(report.Players, PlayerDurationStats{
+			DurationStats: d,
+			ShortWinRate:  winRate(d.ShortWins, d.ShortGames),
+			LongWinRate:   winRate(d.LongWins, d.LongGames),
+		})
+	}
+	return report, nil
+}
+
+func winRate(wins, games int) float64 {
+	if games == 0 {
+		return 0
+	}
+	return float64(wins) / float64(games) * 100
+}
+
+var ErrHeroNotFound = errors.New("hero not found")
+
+type HeroStats struct {
+	HeroID  int     `json:"hero_id"`
+	Hero    string  `json:"hero"`
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	WinRate float64 `json:"win_rate"`
+}
+
+type HeroReport struct {
+	MostPlayed  []HeroStats `json:"most_played"`
+	BestWinRate []HeroStats `json:"best_win_rate"`
+}
+
+// GetHeroes returns the most played heroes and the heroes with the best win rate
+// (at least minGames games), for one player or everyone, optionally in one season only
+func (s *StatsService) GetHeroes(playerID, seasonID string, minGames, limit int) (*HeroReport, error) {
+	rows, err := s.store.GetHeroStats(playerID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]HeroStats, 0, len(rows))
+	for _, h := range rows {
+		stats = append(stats, HeroStats{
+			HeroID:  h.HeroID,
+			Hero:    heroes.LocalizedName(h.HeroID),
+			Games:   h.Games,
+			Wins:    h.Wins,
+			Losses:  h.Games - h.Wins,
+			WinRate: winRate(h.Wins, h.Games),
+		})
+	}
+
+	report := &HeroReport{
+		MostPlayed:  stats[:min(limit, len(stats))],
+		BestWinRate: []HeroStats{},
+	}
+
+	byWinRate := make([]HeroStats, 0, len(stats))
+	for _, h := range stats {
+		if h.Games >= minGames {
+			byWinRate = append(byWinRate, h)
+		}
+	}
+	sort.SliceStable(byWinRate, func(i, j int) bool {
+		return byWinRate[i].WinRate > byWinRate[j].WinRate
+	})
+	report.BestWinRate = append(report.BestWinRate, byWinRate[:min(limit, len(byWinRate))]...)
+
+	return report, nil
+}
+
+type HeroDetails struct {
+	Hero    heroes.Hero             `json:"hero"`
+	Games   int                     `json:"games"`
+	Wins    int                     `json:"wins"`
+	Losses  int                     `json:"losses"`
+	WinRate float64                 `json:"win_rate"`
+	Players []store.HeroPlayerStats `json:"players"`
+}
+
+// GetHero returns the overall record of a hero and who played it, looked up by
+// name or ID, optionally in one season only
+func (s *StatsService) GetHero(name, seasonID string) (*HeroDetails, error) {
+	hero, ok := heroes.Lookup(name)
+	if id, err := strconv.Atoi(name); err == nil {
+		hero, ok = heroes.ByID(id)
+	}
+	if !ok {
+		return nil, ErrHeroNotFound
+	}
+
+	players, err := s.store.GetHeroPlayers(hero.ID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inactive players are hidden from the list, so count the record separately
+	stats, err := s.store.GetHeroStats("", seasonID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &HeroDetails{Hero: hero, Players: players}
+	for _, h := range stats {
+		if h.HeroID == hero.ID {
+			details.Games, details.Wins, details.Losses = h.Games, h.Wins, h.Games-h.Wins
+			details.WinRate = winRate(h.Wins, h.Games)
+		}
+	}
+	return details, nil
+}
+
+var ErrUnknownStat = errors.New("unknown stat, expected kills, deaths, assists, last_hits, net_worth or hero_damage")
+
+func (s *StatsService) GetKDALeaderboard(role, seasonID string, minGames, limit int) ([]store.PerformanceStats, error) {
+	return s.store.GetKDALeaderboard(role, seasonID, minGames, limit)
+}
+
+type GameRecord struct {
+	store.GameRecord
+	Stat string `json:"stat"`
+	Hero string `json:"hero,omitempty"`
+}
+
+// GetRecords returns the best single-game values of one stat, optionally in one season only
+func (s *StatsService) GetRecords(stat, seasonID string, limit int) ([]GameRecord, error) {
+	if !isRecordStat(stat) {
+		return nil, ErrUnknownStat
+	}
+
+	rows, err := s.store.GetRecords(stat, seasonID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]GameRecord, 0, len(rows))
+	for _, r := range rows {
+		record := GameRecord{GameRecord: r, Stat: stat}
+		if r.HeroID != nil {
+			record.Hero = heroes.LocalizedName(*r.HeroID)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// GetTopRecords returns the record of every stat that has been recorded at least
+// once, in one season or all time when seasonID is empty
+func (s *StatsService) GetTopRecords(seasonID string) ([]GameRecord, error) {
+	var records []GameRecord
+	for _, stat := range store.RecordStats {
+		top, err := s.GetRecords(stat, seasonID, 1)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, top...)
+	}
+	return records, nil
+}
+
+func isRecordStat(stat string) bool {
+	for _, name := range store.RecordStats {
+		if name == stat {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// Games shown as recent form on a profile
+	profileFormGames = 10
+	// A best partner needs at least this many games together
+	profilePartnerMinGames = 3
+)
+
+// WinRecord is a win/loss record over some of a player's games
+type WinRecord struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	WinRate float64 `json:"win_rate"`
+}
+
+func (r *WinRecord) add(games, wins int) {
+	r.Games += games
+	r.Wins += wins
+	r.Losses = r.Games - r.Wins
+	r.WinRate = winRate(r.Wins, r.Games)
+}
+
+type RoleRecord struct {
+	Role string `json:"role"`
+	WinRecord
+}
+
+type FormGame struct {
+	store.RecentGame
+	Hero string `json:"hero,omitempty"`
+}
+
+type PlayerProfile struct {
+	PlayerID string    `json:"player_id"`
+	Nickname string    `json:"nickname"`
+	Overall  WinRecord `json:"overall"`
+	// Roles lists every role, also those the player never played
+	Roles   []RoleRecord `json:"roles"`
+	Captain WinRecord    `json:"captain"`
+	Radiant WinRecord    `json:"radiant"`
+	Dire    WinRecord    `json:"dire"`
+	// Form is the last games, newest first
+	Form []FormGame `json:"form"`
+	// BestPartner is the teammate with the best win rate together, nil without enough games
+	BestPartner *store.DuoStats `json:"best_partner"`
+	// MostFrequentOpponent is the player faced most often
+	MostFrequentOpponent *store.OpponentStats `json:"most_frequent_opponent"`
+}
+
+// GetProfile collects the records of a player from their games
+func (s *StatsService) GetProfile(player *store.Player) (*PlayerProfile, error) {
+	splits, err := s.store.GetPlayerSplits(player.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &PlayerProfile{PlayerID: player.ID, Nickname: player.Nickname}
+	roles := make(map[string]*WinRecord)
+	for _, role := range Roles {
+		profile.Roles = append(profile.Roles, RoleRecord{Role: string(role)})
+	}
+	for i := range profile.Roles {
+		roles[profile.Roles[i].Role] = &profile.Roles[i].WinRecord
+	}
+
+	for _, split := range splits {
+		profile.Overall.add(split.Games, split.Wins)
+		if r, ok := roles[split.Role]; ok {
+			r.add(split.Games, split.Wins)
+		}
+		if split.IsCaptain {
+			profile.Captain.add(split.Games, split.Wins)
+		}
+		if split.Team == "RADIANT" {
+			profile.Radiant.add(split.Games, split.Wins)
+		} else {
+			profile.Dire.add(split.Games, split.Wins)
+		}
+	}
+
+	recent, err := s.store.GetRecentGames(player.ID, profileFormGames)
+	if err != nil {
+		return nil, err
+	}
+	profile.Form = make([]FormGame, 0, len(recent))
+	for _, g := range recent {
+		game := FormGame{RecentGame: g}
+		if g.HeroID != nil {
+			game.Hero = heroes.LocalizedName(*g.HeroID)
+		}
+		profile.Form = append(profile.Form, game)
+	}
+
+	// Duos come sorted by win rate, then games
+	duos, err := s.store.GetDuoStats(player.ID, "", profilePartnerMinGames)
+	if err != nil {
+		return nil, err
+	}
+	if len(duos) > 0 {
+		profile.BestPartner = &duos[0]
+	}
+
+	opponents, err := s.store.GetOpponentStats(player.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range opponents {
+		o := &opponents[i]
+		best := profile.MostFrequentOpponent
+		if best == nil || o.Games > best.Games || (o.Games == best.Games && o.Nickname < best.Nickname) {
+			profile.MostFrequentOpponent = o
+		}
+	}
+
+	return profile, nil
+}
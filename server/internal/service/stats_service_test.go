@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"ymb-cloz/internal/store"
+)
+
+func TestComputeStreaks(t *testing.T) {
+	tests := []struct {
+		name    string
+		results string // W and L in chronological order
+		want    Streaks
+	}{
+		{"no games", "", Streaks{}},
+		{"single win", "W", Streaks{CurrentType: "win", Current: 1, LongestWin: 1}},
+		{"current streak is the longest", "WWLWWW", Streaks{CurrentType: "win", Current: 3, LongestWin: 3, LongestLoss: 1}},
+		{"earlier streak is the longest", "WWWWLLW", Streaks{CurrentType: "win", Current: 1, LongestWin: 4, LongestLoss: 2}},
+		{"losing streak", "WLLL", Streaks{CurrentType: "loss", Current: 3, LongestWin: 1, LongestLoss: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []store.PlayerResult
+			for _, r := range tt.results {
+				results = append(results, store.PlayerResult{IsWinner: r == 'W'})
+			}
+			if got := computeStreaks(results); got != tt.want {
+				t.Errorf("computeStreaks(%s) = %+v, want %+v", tt.results, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTelegramLoginDisabled = errors.New("telegram login is not configured")
+	ErrInvalidTelegramLogin  = errors.New("invalid telegram login data")
+	ErrNotAdmin              = errors.New("telegram user is not an admin")
+	ErrInvalidSession        = errors.New("invalid or expired session")
+)
+
+const (
+	// Login widget payloads older than this are rejected to limit replays
+	maxTelegramAuthAge = 24 * time.Hour
+	sessionTTL         = 7 * 24 * time.Hour
+)
+
+// Session is issued after a successful Telegram login and is used as a bearer token
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Principal Principal `json:"principal"`
+}
+
+type sessionClaims struct {
+	Subject   int64  `json:"sub"`
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// AdminList holds the numeric IDs of the Telegram users allowed to change data.
+// Usernames are not accepted, they can be given up and claimed by someone else.
+type AdminList map[int64]bool
+
+// NewAdminList skips entries that are not user IDs and reports them in the error
+func NewAdminList(entries []string) (AdminList, error) {
+	admins := make(AdminList)
+	var invalid []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil {
+			invalid = append(invalid, entry)
+			continue
+		}
+		admins[id] = true
+	}
+	if len(invalid) > 0 {
+		return admins, fmt.Errorf("not telegram user IDs: %s", strings.Join(invalid, ", "))
+	}
+	return admins, nil
+}
+
+// Contains reports whether a Telegram user is listed
+func (a AdminList) Contains(id int64) bool {
+	return a[id]
+}
+
+// TelegramAuth verifies Telegram Login Widget payloads and issues signed sessions
+type TelegramAuth struct {
+	botToken   string
+	admins     AdminList
+	sessionKey []byte
+	now        func() time.Time
+}
+
+// NewTelegramAuth signs sessions with sessionSecret, or without one with a key
+// derived from the bot token, so rotating the token also ends all sessions
+func NewTelegramAuth(botToken string, admins AdminList, sessionSecret string) *TelegramAuth {
+	t := &TelegramAuth{
+		botToken: botToken,
+		admins:   admins,
+		now:      time.Now,
+	}
+
+	if sessionSecret != "" {
+		t.sessionKey = []byte(sessionSecret)
+	} else {
+		mac := hmac.New(sha256.New, []byte(botToken))
+		mac.Write([]byte("ymb-cloz session"))
+		t.sessionKey = mac.Sum(nil)
+	}
+	return t
+}
+
+// Login checks the widget payload signature and issues an admin session.
+// Fields are the payload as received, including "hash".
+func (t *TelegramAuth) Login(fields map[string]string) (*Session, error) {
+	if t.botToken == "" {
+		return nil, ErrTelegramLoginDisabled
+	}
+	if err := t.verify(fields); err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidTelegramLogin
+	}
+	if !t.admins.Contains(id) {
+		return nil, ErrNotAdmin
+	}
+
+	name := fields["username"]
+	if name == "" {
+		name = strings.TrimSpace(fields["first_name"] + " " + fields["last_name"])
+	}
+	principal := Principal{Name: "telegram:" + name, Scope: ScopeAdmin}
+	expiresAt := t.now().Add(sessionTTL).Truncate(time.Second)
+
+	token, err := t.sign(sessionClaims{Subject: id, Name: principal.Name, Scope: principal.Scope, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Token: token, ExpiresAt: expiresAt, Principal: principal}, nil
+}
+
+// verify implements https://core.telegram.org/widgets/login#checking-authorization
+func (t *TelegramAuth) verify(fields map[string]string) error {
+	hash, ok := fields["hash"]
+	if !ok || fields["id"] == "" {
+		return ErrInvalidTelegramLogin
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + "=" + fields[key]
+	}
+
+	secret := sha256.Sum256([]byte(t.botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(hash))) {
+		return ErrInvalidTelegramLogin
+	}
+
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return ErrInvalidTelegramLogin
+	}
+	if t.now().Sub(time.Unix(authDate, 0)) > maxTelegramAuthAge {
+		return fmt.Errorf("%w: login is too old, please log in again", ErrInvalidTelegramLogin)
+	}
+	return nil
+}
+
+// sign encodes claims as base64url(JSON) "." base64url(HMAC-SHA256)
+func (t *TelegramAuth) sign(claims sessionClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %v", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.mac(encoded)), nil
+}
+
+func (t *TelegramAuth) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, t.sessionKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// VerifySession returns the principal of a session token issued by Login
+func (t *TelegramAuth) VerifySession(token string) (*Principal, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, t.mac(payload)) {
+		return nil, ErrInvalidSession
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	var claims sessionClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, ErrInvalidSession
+	}
+	if t.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidSession
+	}
+
+	// Admins removed from the list lose access before their session expires
+	if !t.admins.Contains(claims.Subject) {
+		return nil, ErrInvalidSession
+	}
+
+	return &Principal{Name: claims.Name, Scope: claims.Scope}, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBotToken = "123456:TEST-token"
+
+// testLoginFields returns a payload signed with testBotToken at 1700000000
+func testLoginFields() map[string]string {
+	return map[string]string{
+		"id":         "42",
+		"first_name": "Ann",
+		"username":   "ann",
+		"auth_date":  "1700000000",
+		"hash":       "1f8b8b478e653c79711d0e4b73187c8831312f701d1f5cb5c52a11bd0e3188c0",
+	}
+}
+
+func newTestTelegramAuth(botToken string, admins ...int64) *TelegramAuth {
+	list := make(AdminList)
+	for _, id := range admins {
+		list[id] = true
+	}
+	auth := NewTelegramAuth(botToken, list, "")
+	auth.now = func() time.Time { return time.Unix(1700000000+60, 0) }
+	return auth
+}
+
+func TestTelegramLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		botToken string
+		admins   []int64
+		modify   func(fields map[string]string)
+		age      time.Duration
+		want     error
+	}{
+		{name: "valid", botToken: testBotToken, admins: []int64{42}},
+		{
+			name:     "uppercase hash",
+			botToken: testBotToken,
+			admins:   []int64{42},
+			modify:   func(f map[string]string) { f["hash"] = strings.ToUpper(f["hash"]) },
+		},
+		{
+			name:     "tampered field",
+			botToken: testBotToken,
+			admins:   []int64{42},
+			modify:   func(f map[string]string) { f["username"] = "admin" },
+			want:     ErrInvalidTelegramLogin,
+		},
+		{
+			name:     "missing hash",
+			botToken: testBotToken,
+			admins:   []int64{42},
+			modify:   func(f map[string]string) { delete(f, "hash") },
+			want:     ErrInvalidTelegramLogin,
+		},
+		{
+			name:     "signed with another token",
+			botToken: "654321:OTHER-token",
+			admins:   []int64{42},
+			want:     ErrInvalidTelegramLogin,
+		},
+		{
+			name:     "too old",
+			botToken: testBotToken,
+			admins:   []int64{42},
+			age:      25 * time.Hour,
+			want:     ErrInvalidTelegramLogin,
+		},
+		{name: "not an admin", botToken: testBotToken, admins: []int64{7}, want: ErrNotAdmin},
+		{name: "not configured", want: ErrTelegramLoginDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newTestTelegramAuth(tt.botToken, tt.admins...)
+			if tt.age > 0 {
+				auth.now = func() time.Time { return time.Unix(1700000000, 0).Add(tt.age) }
+			}
+			fields := testLoginFields()
+			if tt.modify != nil {
+				tt.modify(fields)
+			}
+
+			session, err := auth.Login(fields)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+			if session.Principal.Name != "telegram:ann" || session.Principal.Scope != ScopeAdmin {
+				t.Errorf("principal = %+v, want telegram:ann with admin scope", session.Principal)
+			}
+		})
+	}
+}
+
+func TestTelegramVerifySession(t *testing.T) {
+	auth := newTestTelegramAuth(testBotToken, 42)
+	session, err := auth.Login(testLoginFields())
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		setup func(auth *TelegramAuth)
+		want  error
+	}{
+		{name: "valid", token: session.Token},
+		{name: "tampered", token: "x" + session.Token, want: ErrInvalidSession},
+		{name: "not a token", token: "garbage", want: ErrInvalidSession},
+		{
+			name:  "expired",
+			token: session.Token,
+			setup: func(auth *TelegramAuth) { auth.now = func() time.Time { return session.ExpiresAt } },
+			want:  ErrInvalidSession,
+		},
+		{
+			name:  "admin removed",
+			token: session.Token,
+			setup: func(auth *TelegramAuth) { delete(auth.admins, 42) },
+			want:  ErrInvalidSession,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newTestTelegramAuth(testBotToken, 42)
+			if tt.setup != nil {
+				tt.setup(auth)
+			}
+
+			principal, err := auth.VerifySession(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifySession() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && *principal != session.Principal {
+				t.Errorf("principal = %+v, want %+v", *principal, session.Principal)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrDatabaseNotEmpty = errors.New("database already contains players or games")
+
+// Backup is the full content of the database except ratings, which are
+// derived from the games and recomputed on restore
+type Backup struct {
+	Version         int                 `json:"version"`
+	CreatedAt       time.Time           `json:"created_at"`
+	Players         []BackupPlayer      `json:"players"`
+	Aliases         []BackupAlias       `json:"aliases"`
+	Seasons         []Season            `json:"seasons"`
+	SeasonStandings []BackupStanding    `json:"season_standings"`
+	Games           []BackupGame        `json:"games"`
+	GamePlayers     []BackupGamePlayer  `json:"game_players"`
+	PendingGames    []BackupPendingGame `json:"pending_games"`
+}
+
+type BackupPlayer struct {
+	ID             string     `json:"id"`
+	Nickname       string     `json:"nickname"`
+	IsActive       bool       `json:"is_active"`
+	SteamAccountID *int64     `json:"steam_account_id,omitempty"`
+	GamesPlayed    []string   `json:"games_played"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+}
+
+type BackupAlias struct {
+	Alias     string     `json:"alias"`
+	PlayerID  string     `json:"player_id"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type BackupStanding struct {
+	SeasonID string `json:"season_id"`
+	SeasonStanding
+}
+
+type BackupGame struct {
+	ID        string     `json:"id"`
+	Timestamp time.Time  `json:"timestamp"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Winner    string     `json:"winner"`
+	SeasonID  *string    `json:"season_id,omitempty"`
+	MatchID   *int64     `json:"match_id,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type BackupGamePlayer struct {
+	GameID     string `json:"game_id"`
+	PlayerID   string `json:"player_id"`
+	Team       string `json:"team"`
+	Role       string `json:"role"`
+	IsCaptain  bool   `json:"is_captain"`
+	IsWinner   bool   `json:"is_winner"`
+	HeroID     *int   `json:"hero_id,omitempty"`
+	Kills      *int   `json:"kills,omitempty"`
+	Deaths     *int   `json:"deaths,omitempty"`
+	Assists    *int   `json:"assists,omitempty"`
+	LastHits   *int   `json:"last_hits,omitempty"`
+	NetWorth   *int   `json:"net_worth,omitempty"`
+	HeroDamage *int   `json:"hero_damage,omitempty"`
+}
+
+type BackupPendingGame struct {
+	ID             string          `json:"id"`
+	RadiantPlayers json.RawMessage `json:"radiant_players"`
+	DirePlayers    json.RawMessage `json:"dire_players"`
+	Source         string          `json:"source"`
+	CreatedAt      *time.Time      `json:"created_at,omitempty"`
+}
+
+type BackupStore struct {
+	db *sql.DB
+}
+
+func NewBackupStore(db *sql.DB) *BackupStore {
+	return &BackupStore{db: db}
+}
+
+func (s *BackupStore) BeginTx() (*sql.Tx, error) {
+	return s.db.Begin()
+}
+
+// Dump reads every table from a single snapshot
+func (s *BackupStore) Dump() (*Backup, error) {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	backup := &Backup{
+		Players:         []BackupPlayer{},
+		Aliases:         []BackupAlias{},
+		Seasons:         []Season{},
+		SeasonStandings: []BackupStanding{},
+		Games:           []BackupGame{},
+		GamePlayers:     []BackupGamePlayer{},
+		PendingGames:    []BackupPendingGame{},
+	}
+
+	for _, section := range []struct {
+		name  string
+		query string
+		scan  func(rows *sql.Rows) error
+	}{
+		{"players", `SELECT id, nickname, is_active, steam_account_id, COALESCE(games_played, ARRAY[]::UUID[]), created_at FROM players ORDER BY created_at, id`,
+			func(rows *sql.Rows) error {
+				var p BackupPlayer
+				if err := rows.Scan(&p.ID, &p.Nickname, &p.IsActive, &p.SteamAccountID, pq.Array(&p.GamesPlayed), &p.CreatedAt); err != nil {
+					return err
+				}
+				if p.GamesPlayed == nil {
+					p.GamesPlayed = []string{}
+				}
+				backup.Players = append(backup.Players, p)
+				return nil
+			}},
+		{"aliases", `SELECT alias, player_id, created_at FROM player_aliases ORDER BY created_at, alias`,
+			func(rows *sql.Rows) error {
+				var a BackupAlias
+				if err := rows.Scan(&a.Alias, &a.PlayerID, &a.CreatedAt); err != nil {
+					return err
+				}
+				backup.Aliases = append(backup.Aliases, a)
+				return nil
+			}},
+		{"seasons", `SELECT ` + seasonColumns + ` FROM seasons ORDER BY number`,
+			func(rows *sql.Rows) error {
+				var season Season
+				if err := rows.Scan(&season.ID, &season.Number, &season.Name, &season.StartedAt, &season.EndedAt); err != nil {
+					return err
+				}
+				backup.Seasons = append(backup.Seasons, season)
+				return nil
+			}},
+		{"season standings", `SELECT season_id, position, player_id, nickname, games, wins, losses, win_rate FROM season_standings ORDER BY season_id, position`,
+			func(rows *sql.Rows) error {
+				var st BackupStanding
+				if err := rows.Scan(&st.SeasonID, &st.Position, &st.PlayerID, &st.Nickname, &st.Games, &st.Wins, &st.Losses, &st.WinRate); err != nil {
+					return err
+				}
+				backup.SeasonStandings = append(backup.SeasonStandings, st)
+				return nil
+			}},
+		{"games", `SELECT id, timestamp, end_time, winner, season_id, match_id, created_at FROM games ORDER BY timestamp, id`,
+			func(rows *sql.Rows) error {
+				var g BackupGame
+				if err := rows.Scan(&g.ID, &g.Timestamp, &g.EndTime, &g.Winner, &g.SeasonID, &g.MatchID, &g.CreatedAt); err != nil {
+					return err
+				}
+				backup.Games = append(backup.Games, g)
+				return nil
+			}},
+		{"game players", `
+			SELECT gp.game_id, gp.player_id, gp.team, gp.role, gp.is_captain, gp.is_winner, gp.hero_id,
+				gp.kills, gp.deaths, gp.assists, gp.last_hits, gp.net_worth, gp.hero_damage
+			FROM game_players gp
+			JOIN games g ON g.id = gp.game_id
+			ORDER BY g.timestamp, gp.game_id, gp.team DESC, gp.role`,
+			func(rows *sql.Rows) error {
+				var gp BackupGamePlayer
+				if err := rows.Scan(&gp.GameID, &gp.PlayerID, &gp.Team, &gp.Role, &gp.IsCaptain, &gp.IsWinner, &gp.HeroID,
+					&gp.Kills, &gp.Deaths, &gp.Assists, &gp.LastHits, &gp.NetWorth, &gp.HeroDamage); err != nil {
+					return err
+				}
+				backup.GamePlayers = append(backup.GamePlayers, gp)
+				return nil
+			}},
+		{"pending games", `SELECT id, radiant_players, dire_players, source, created_at FROM pending_games ORDER BY created_at, id`,
+			func(rows *sql.Rows) error {
+				var pg BackupPendingGame
+				var radiant, dire []byte
+				if err := rows.Scan(&pg.ID, &radiant, &dire, &pg.Source, &pg.CreatedAt); err != nil {
+					return err
+				}
+				pg.RadiantPlayers, pg.DirePlayers = radiant, dire
+				backup.PendingGames = append(backup.PendingGames, pg)
+				return nil
+			}},
+	} {
+		rows, err := tx.Query(section.query)
+		if err != nil {
+			return nil, fmt.Errorf("error querying %s: %v", section.name, err)
+		}
+		for rows.Next() {
+			if err := section.scan(rows); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning %s: %v", section.name, err)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating %s: %v", section.name, err)
+		}
+	}
+
+	return backup, nil
+}
+
+// RestoreTx writes a backup into a database without players and games. Seasons
+// in the backup replace the one created by the migrations.
+func (s *BackupStore) RestoreTx(tx *sql.Tx, backup *Backup) error {
+	var existing bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM players)
+			OR EXISTS (SELECT 1 FROM games)
+			OR EXISTS (SELECT 1 FROM pending_games)`).Scan(&existing)
+	if err != nil {
+		return fmt.Errorf("error checking existing data: %v", err)
+	}
+	if existing {
+		return ErrDatabaseNotEmpty
+	}
+
+	if len(backup.Seasons) > 0 {
+		if _, err := tx.Exec("DELETE FROM season_standings"); err != nil {
+			return fmt.Errorf("error clearing season standings: %v", err)
+		}
+		if _, err := tx.Exec("DELETE FROM seasons"); err != nil {
+			return fmt.Errorf("error clearing seasons: %v", err)
+		}
+	}
+
+	for _, p := range backup.Players {
+		_, err := tx.Exec(`
+			INSERT INTO players (id, nickname, is_active, steam_account_id, games_played, created_at)
+			VALUES ($1, $2, $3, $4, $5::UUID[], COALESCE($6, CURRENT_TIMESTAMP))`,
+			p.ID, p.Nickname, p.IsActive, p.SteamAccountID, pq.Array(p.GamesPlayed), p.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring player %s: %v", p.Nickname, err)
+		}
+	}
+
+	for _, a := range backup.Aliases {
+		_, err := tx.Exec(`
+			INSERT INTO player_aliases (alias, player_id, created_at)
+			VALUES ($1, $2, COALESCE($3, CURRENT_TIMESTAMP))`,
+			a.Alias, a.PlayerID, a.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring alias %s: %v", a.Alias, err)
+		}
+	}
+
+	for _, season := range backup.Seasons {
+		_, err := tx.Exec(`
+			INSERT INTO seasons (`+seasonColumns+`)
+			VALUES ($1, $2, $3, $4, $5)`,
+			season.ID, season.Number, season.Name, season.StartedAt, season.EndedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring season %d: %v", season.Number, err)
+		}
+	}
+
+	for _, st := range backup.SeasonStandings {
+		_, err := tx.Exec(`
+			INSERT INTO season_standings (season_id, position, player_id, nickname, games, wins, losses, win_rate)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			st.SeasonID, st.Position, st.PlayerID, st.Nickname, st.Games, st.Wins, st.Losses, st.WinRate)
+		if err != nil {
+			return fmt.Errorf("error restoring standings of season %s: %v", st.SeasonID, err)
+		}
+	}
+
+	for _, g := range backup.Games {
+		_, err := tx.Exec(`
+			INSERT INTO games (id, timestamp, end_time, winner, season_id, match_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, CURRENT_TIMESTAMP))`,
+			g.ID, g.Timestamp, g.EndTime, g.Winner, g.SeasonID, g.MatchID, g.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring game %s: %v", g.ID, err)
+		}
+	}
+
+	for _, gp := range backup.GamePlayers {
+		_, err := tx.Exec(`
+			INSERT INTO game_players (game_id, player_id, team, role, is_captain, is_winner, hero_id,
+				kills, deaths, assists, last_hits, net_worth, hero_damage)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			gp.GameID, gp.PlayerID, gp.Team, gp.Role, gp.IsCaptain, gp.IsWinner, gp.HeroID,
+			gp.Kills, gp.Deaths, gp.Assists, gp.LastHits, gp.NetWorth, gp.HeroDamage)
+		if err != nil {
+			return fmt.Errorf("error restoring player %s of game %s: %v", gp.PlayerID, gp.GameID, err)
+		}
+	}
+
+	for _, pg := range backup.PendingGames {
+		_, err := tx.Exec(`
+			INSERT INTO pending_games (id, radiant_players, dire_players, source, created_at)
+			VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP))`,
+			pg.ID, string(pg.RadiantPlayers), string(pg.DirePlayers), pg.Source, pg.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring pending game %s: %v", pg.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// GamesPlayedMismatchesTx lists players whose games_played differs from their game_players rows
+func (s *BackupStore) GamesPlayedMismatchesTx(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT p.nickname
+		FROM players p
+		WHERE ARRAY(SELECT DISTINCT g FROM unnest(COALESCE(p.games_played, ARRAY[]::UUID[])) g ORDER BY g)
+			IS DISTINCT FROM ARRAY(SELECT gp.game_id FROM game_players gp WHERE gp.player_id = p.id ORDER BY gp.game_id)
+			OR cardinality(p.games_played) <> (SELECT COUNT(*) FROM game_players gp WHERE gp.player_id = p.id)
+		ORDER BY p.nickname`)
+	if err != nil {
+		return nil, fmt.Errorf("error checking games_played: %v", err)
+	}
+	defer rows.Close()
+
+	var nicknames []string
+	for rows.Next() {
+		var nickname string
+		if err := rows.Scan(&nickname); err != nil {
+			return nil, fmt.Errorf("error scanning player: %v", err)
+		}
+		nicknames = append(nicknames, nickname)
+	}
+	return nicknames, rows.Err()
+}
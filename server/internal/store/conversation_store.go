@@ -0,0 +1,49 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type ConversationStore struct {
+	db *sql.DB
+}
+
+func NewConversationStore(db *sql.DB) *ConversationStore {
+	return &ConversationStore{db: db}
+}
+
+// GetConversation returns the state saved after maxAge ago, sql.ErrNoRows if there is none
+func (s *ConversationStore) GetConversation(chatID, userID int64, kind string, maxAge time.Duration) ([]byte, error) {
+	var state []byte
+	err := s.db.QueryRow(`
+		SELECT state FROM bot_conversations
+		WHERE chat_id = $1 AND user_id = $2 AND kind = $3 AND updated_at > $4`,
+		chatID, userID, kind, time.Now().Add(-maxAge)).Scan(&state)
+	return state, err
+}
+
+func (s *ConversationStore) SaveConversation(chatID, userID int64, kind string, state []byte) error {
+	_, err := s.db.Exec(`
+		INSERT INTO bot_conversations (chat_id, user_id, kind, state, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (chat_id, user_id, kind) DO UPDATE SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at`,
+		chatID, userID, kind, string(state))
+	if err != nil {
+		return fmt.Errorf("error saving conversation: %v", err)
+	}
+	return nil
+}
+
+// DeleteConversation also clears conversations nobody finished, of any chat
+func (s *ConversationStore) DeleteConversation(chatID, userID int64, kind string, maxAge time.Duration) error {
+	_, err := s.db.Exec(`
+		DELETE FROM bot_conversations
+		WHERE (chat_id = $1 AND user_id = $2 AND kind = $3) OR updated_at <= $4`,
+		chatID, userID, kind, time.Now().Add(-maxAge))
+	if err != nil {
+		return fmt.Errorf("error deleting conversation: %v", err)
+	}
+	return nil
+}
@@ -2,19 +2,43 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
+
+	"ymb-cloz/internal/models"
 
 	"github.com/lib/pq"
 )
 
+var ErrDuplicateMatch = errors.New("match has already been recorded")
+
 type GameStore interface {
 	BeginTx() (*sql.Tx, error)
 	CreateGameTx(tx *sql.Tx, game *Game) error
-	GetOrCreatePlayerTx(tx *sql.Tx, nickname string) (string, error)
+	FindPlayerByNicknameTx(tx *sql.Tx, nickname string) (string, error)
+	ListPlayerNamesTx(tx *sql.Tx) ([]PlayerName, error)
+	CreatePlayerTx(tx *sql.Tx, nickname string) (string, error)
 	GetPlayerByIDTx(tx *sql.Tx, id string) (bool, error)
 	CreateGamePlayersTx(tx *sql.Tx, gameID string, players []GamePlayer) error
 	UpdatePlayersGamesTx(tx *sql.Tx, gameID string, playerIDs []string) error
+	LockGameTx(tx *sql.Tx, id string) (*Game, error)
+	HasGamesAfterTx(tx *sql.Tx, game *Game) (bool, error)
+	MatchRecordedTx(tx *sql.Tx, matchID int64) (bool, error)
+	UpdateGameTx(tx *sql.Tx, game *Game) error
+	RefreshStandingsTx(tx *sql.Tx, seasonIDs ...*string) error
+	DeleteGameTx(tx *sql.Tx, id string) error
+	DeleteGamePlayersTx(tx *sql.Tx, gameID string) error
+	RemoveGameFromPlayersTx(tx *sql.Tx, gameID string) error
+	ListGames(filter GameFilter) ([]Game, int, error)
+	GetGame(id string) (*Game, error)
+	GetGamePlayers(gameIDs []string) ([]GamePlayer, error)
+	CreatePendingGame(game *models.PendingGame) error
+	ListPendingGames() ([]models.PendingGame, error)
+	DeletePendingGameTx(tx *sql.Tx, id string) (bool, error)
 }
 
 type PostgresGameStore struct {
@@ -26,41 +50,90 @@ func NewGameStore(db *sql.DB) GameStore {
 }
 
 type Game struct {
-	ID        string
-	Timestamp string
+	ID string
+	// Timestamp is when the game started, zero on create means now
+	Timestamp time.Time
+	EndTime   *time.Time
 	Winner    string
+	SeasonID  *string
+	// MatchID is the Dota match ID of imported games
+	MatchID *int64
+}
+
+// startArg passes a zero start time as NULL so the database default applies
+func (g *Game) startArg() interface{} {
+	if g.Timestamp.IsZero() {
+		return nil
+	}
+	return g.Timestamp
 }
 
 type GamePlayer struct {
 	GameID    string
 	PlayerID  string
+	Nickname  string
 	Team      string
 	Role      string
 	IsCaptain bool
 	IsWinner  bool
+	HeroID    *int
+	Stats     *models.PerformanceStats
+	// Ratings are nil until the game has been rated
+	RatingBefore *float64
+	RatingAfter  *float64
 }
 
 func (s *PostgresGameStore) BeginTx() (*sql.Tx, error) {
 	return s.db.Begin()
 }
 
-func (s *PostgresGameStore) GetOrCreatePlayerTx(tx *sql.Tx, nickname string) (string, error) {
+// FindPlayerByNicknameTx resolves an exact nickname, falling back to a
+// case-insensitive match on nicknames and aliases. Returns sql.ErrNoRows if nothing matches.
+func (s *PostgresGameStore) FindPlayerByNicknameTx(tx *sql.Tx, nickname string) (string, error) {
 	var playerID string
 
-	// Try to find existing player
 	err := tx.QueryRow("SELECT id FROM players WHERE nickname = $1", nickname).Scan(&playerID)
-	if err == nil {
-		// Player found
-		return playerID, nil
+	if err != sql.ErrNoRows {
+		return playerID, err
 	}
 
-	if err != sql.ErrNoRows {
-		log.Printf("error checking player existence: %v", err)
+	query := `
+		SELECT id FROM players WHERE LOWER(nickname) = LOWER($1)
+		UNION
+		SELECT player_id FROM player_aliases WHERE LOWER(alias) = LOWER($1)`
+
+	rows, err := tx.Query(query, nickname)
+	if err != nil {
 		return "", fmt.Errorf("error checking player existence: %v", err)
 	}
+	defer rows.Close()
 
-	// Player not found, create new one
-	err = tx.QueryRow(`
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", fmt.Errorf("error scanning player: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	// Ambiguous case-insensitive matches are left to fuzzy matching
+	if len(ids) != 1 {
+		return "", sql.ErrNoRows
+	}
+	return ids[0], nil
+}
+
+func (s *PostgresGameStore) ListPlayerNamesTx(tx *sql.Tx) ([]PlayerName, error) {
+	return listPlayerNames(tx)
+}
+
+func (s *PostgresGameStore) CreatePlayerTx(tx *sql.Tx, nickname string) (string, error) {
+	var playerID string
+	err := tx.QueryRow(`
 		INSERT INTO players (nickname)
 		VALUES ($1)
 		RETURNING id`, nickname).Scan(&playerID)
@@ -83,11 +156,21 @@ func (s *PostgresGameStore) GetPlayerByIDTx(tx *sql.Tx, id string) (bool, error)
 
 func (s *PostgresGameStore) CreateGameTx(tx *sql.Tx, game *Game) error {
 	query := `
-		INSERT INTO games (winner)
-		VALUES ($1)
-		RETURNING id, timestamp`
+		WITH start AS (
+			SELECT COALESCE($2::TIMESTAMPTZ, CURRENT_TIMESTAMP) AS ts
+		)
+		INSERT INTO games (winner, timestamp, end_time, match_id, season_id)
+		SELECT $1, start.ts, $3, $4,
+			-- Backdated games belong to the season they were played in, games
+			-- played before the first season belong to none
+			(SELECT id FROM seasons WHERE started_at <= start.ts AND (ended_at IS NULL OR ended_at > start.ts))
+		FROM start
+		RETURNING id, timestamp, season_id`
 
-	err := tx.QueryRow(query, game.Winner).Scan(&game.ID, &game.Timestamp)
+	err := tx.QueryRow(query, game.Winner, game.startArg(), game.EndTime, game.MatchID).Scan(&game.ID, &game.Timestamp, &game.SeasonID)
+	if isUniqueViolation(err) {
+		return ErrDuplicateMatch
+	}
 	if err != nil {
 		return fmt.Errorf("error creating game: %v", err)
 	}
@@ -97,11 +180,19 @@ func (s *PostgresGameStore) CreateGameTx(tx *sql.Tx, game *Game) error {
 
 func (s *PostgresGameStore) CreateGamePlayersTx(tx *sql.Tx, gameID string, players []GamePlayer) error {
 	query := `
-		INSERT INTO game_players (game_id, player_id, team, role, is_captain, is_winner)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO game_players (game_id, player_id, team, role, is_captain, is_winner, hero_id,
+			kills, deaths, assists, last_hits, net_worth, hero_damage)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 
 	for _, player := range players {
-		_, err := tx.Exec(query, gameID, player.PlayerID, player.Team, player.Role, player.IsCaptain, player.IsWinner)
+		var kills, deaths, assists, lastHits, netWorth, heroDamage *int
+		if st := player.Stats; st != nil {
+			kills, deaths, assists = &st.Kills, &st.Deaths, &st.Assists
+			lastHits, netWorth, heroDamage = st.LastHits, st.NetWorth, st.HeroDamage
+		}
+
+		_, err := tx.Exec(query, gameID, player.PlayerID, player.Team, player.Role, player.IsCaptain, player.IsWinner, player.HeroID,
+			kills, deaths, assists, lastHits, netWorth, heroDamage)
 		if err != nil {
 			return fmt.Errorf("error creating game player: %v", err)
 		}
@@ -123,3 +214,318 @@ func (s *PostgresGameStore) UpdatePlayersGamesTx(tx *sql.Tx, gameID string, play
 
 	return nil
 }
+
+// LockGameTx locks the game row for the rest of the transaction and returns its
+// recorded times and season, or sql.ErrNoRows if it does not exist
+func (s *PostgresGameStore) LockGameTx(tx *sql.Tx, id string) (*Game, error) {
+	game := &Game{ID: id}
+	err := tx.QueryRow("SELECT timestamp, end_time, season_id FROM games WHERE id = $1 FOR UPDATE", id).
+		Scan(&game.Timestamp, &game.EndTime, &game.SeasonID)
+	if err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// HasGamesAfterTx reports whether any other game started after the given one
+func (s *PostgresGameStore) HasGamesAfterTx(tx *sql.Tx, game *Game) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM games WHERE timestamp > $1 AND id <> $2)", game.Timestamp, game.ID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking later games: %v", err)
+	}
+	return exists, nil
+}
+
+// MatchRecordedTx tells whether a game was already recorded for the Dota match
+func (s *PostgresGameStore) MatchRecordedTx(tx *sql.Tx, matchID int64) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM games WHERE match_id = $1)", matchID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking match: %v", err)
+	}
+	return exists, nil
+}
+
+func (s *PostgresGameStore) UpdateGameTx(tx *sql.Tx, game *Game) error {
+	query := `
+		UPDATE games
+		SET winner = $2,
+			timestamp = COALESCE($3::TIMESTAMPTZ, timestamp),
+			end_time = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN end_time ELSE $4 END,
+			season_id = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN season_id ELSE
+				(SELECT id FROM seasons WHERE started_at <= $3::TIMESTAMPTZ AND (ended_at IS NULL OR ended_at > $3::TIMESTAMPTZ)) END
+		WHERE id = $1
+		RETURNING timestamp, end_time, season_id`
+
+	// Without a new start time the recorded times are kept
+	err := tx.QueryRow(query, game.ID, game.Winner, game.startArg(), game.EndTime).Scan(&game.Timestamp, &game.EndTime, &game.SeasonID)
+	if err != nil {
+		return fmt.Errorf("error updating game: %v", err)
+	}
+
+	return nil
+}
+
+// RefreshStandingsTx rebuilds the archived standings of the given seasons that
+// are already closed, once their games have changed. Open seasons and nil IDs are skipped.
+func (s *PostgresGameStore) RefreshStandingsTx(tx *sql.Tx, seasonIDs ...*string) error {
+	refreshed := make(map[string]bool)
+	for _, id := range seasonIDs {
+		if id == nil || refreshed[*id] {
+			continue
+		}
+		refreshed[*id] = true
+
+		var closed bool
+		err := tx.QueryRow("SELECT ended_at IS NOT NULL FROM seasons WHERE id = $1 FOR UPDATE", *id).Scan(&closed)
+		if err != nil {
+			return fmt.Errorf("error checking season: %v", err)
+		}
+		if !closed {
+			continue
+		}
+
+		if _, err := tx.Exec("DELETE FROM season_standings WHERE season_id = $1", *id); err != nil {
+			return fmt.Errorf("error clearing standings: %v", err)
+		}
+		if _, err := tx.Exec(archiveStandingsQuery, *id); err != nil {
+			return fmt.Errorf("error archiving standings: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresGameStore) DeleteGameTx(tx *sql.Tx, id string) error {
+	_, err := tx.Exec("DELETE FROM games WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting game: %v", err)
+	}
+	return nil
+}
+
+func (s *PostgresGameStore) DeleteGamePlayersTx(tx *sql.Tx, gameID string) error {
+	_, err := tx.Exec("DELETE FROM game_players WHERE game_id = $1", gameID)
+	if err != nil {
+		return fmt.Errorf("error deleting game players: %v", err)
+	}
+	return nil
+}
+
+// RemoveGameFromPlayersTx drops the game ID from games_played of every player that references it
+func (s *PostgresGameStore) RemoveGameFromPlayersTx(tx *sql.Tx, gameID string) error {
+	query := `
+		UPDATE players
+		SET games_played = array_remove(games_played, $1::UUID)
+		WHERE $1::UUID = ANY(games_played)`
+
+	_, err := tx.Exec(query, gameID)
+	if err != nil {
+		return fmt.Errorf("error removing game from players: %v", err)
+	}
+	return nil
+}
+
+type GameFilter struct {
+	PlayerID  string
+	Role      string
+	IsCaptain bool
+	Winner    string
+	SeasonID  string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// where builds the WHERE clause for games aliased as "g"
+func (f GameFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.PlayerID != "" {
+		args = append(args, f.PlayerID)
+		cond := fmt.Sprintf("fp.player_id = $%d", len(args))
+		if f.Role != "" {
+			args = append(args, f.Role)
+			cond += fmt.Sprintf(" AND fp.role = $%d", len(args))
+		}
+		if f.IsCaptain {
+			cond += " AND fp.is_captain = true"
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM game_players fp WHERE fp.game_id = g.id AND "+cond+")")
+	}
+	if f.Winner != "" {
+		args = append(args, f.Winner)
+		conds = append(conds, fmt.Sprintf("g.winner = $%d", len(args)))
+	}
+	if f.SeasonID != "" {
+		args = append(args, f.SeasonID)
+		conds = append(conds, fmt.Sprintf("g.season_id = $%d", len(args)))
+	}
+	if f.From != nil {
+		args = append(args, *f.From)
+		conds = append(conds, fmt.Sprintf("g.timestamp >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, *f.To)
+		conds = append(conds, fmt.Sprintf("g.timestamp <= $%d", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *PostgresGameStore) ListGames(filter GameFilter) ([]Game, int, error) {
+	where, args := filter.where()
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT g.id, g.timestamp, g.end_time, g.winner, g.season_id, g.match_id, COUNT(*) OVER() AS total
+		FROM games g
+		%s
+		ORDER BY g.timestamp DESC, g.id
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying games: %v", err)
+	}
+	defer rows.Close()
+
+	var games []Game
+	total := 0
+	for rows.Next() {
+		var game Game
+		if err := rows.Scan(&game.ID, &game.Timestamp, &game.EndTime, &game.Winner, &game.SeasonID, &game.MatchID, &total); err != nil {
+			return nil, 0, fmt.Errorf("error scanning game: %v", err)
+		}
+		games = append(games, game)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating games: %v", err)
+	}
+
+	// An offset past the end returns no rows, so count separately
+	if len(games) == 0 && filter.Offset > 0 {
+		countArgs := args[:len(args)-2]
+		err := s.db.QueryRow("SELECT COUNT(*) FROM games g "+where, countArgs...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error counting games: %v", err)
+		}
+	}
+
+	return games, total, nil
+}
+
+func (s *PostgresGameStore) GetGame(id string) (*Game, error) {
+	var game Game
+	err := s.db.QueryRow("SELECT id, timestamp, end_time, winner, season_id, match_id FROM games WHERE id = $1", id).
+		Scan(&game.ID, &game.Timestamp, &game.EndTime, &game.Winner, &game.SeasonID, &game.MatchID)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+func (s *PostgresGameStore) GetGamePlayers(gameIDs []string) ([]GamePlayer, error) {
+	query := `
+		SELECT gp.game_id, gp.player_id, p.nickname, gp.team, gp.role, gp.is_captain, gp.is_winner,
+			gp.hero_id, gp.rating_before, gp.rating_after,
+			gp.kills, gp.deaths, gp.assists, gp.last_hits, gp.net_worth, gp.hero_damage
+		FROM game_players gp
+		JOIN players p ON p.id = gp.player_id
+		WHERE gp.game_id = ANY($1)
+		ORDER BY gp.game_id, gp.team DESC, array_position(ARRAY['carry', 'mid', 'offlane', 'pos4', 'pos5']::VARCHAR[], gp.role)`
+
+	rows, err := s.db.Query(query, pq.Array(gameIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying game players: %v", err)
+	}
+	defer rows.Close()
+
+	var players []GamePlayer
+	for rows.Next() {
+		var player GamePlayer
+		var kills, deaths, assists sql.NullInt64
+		var stats models.PerformanceStats
+		if err := rows.Scan(&player.GameID, &player.PlayerID, &player.Nickname, &player.Team, &player.Role, &player.IsCaptain, &player.IsWinner,
+			&player.HeroID, &player.RatingBefore, &player.RatingAfter,
+			&kills, &deaths, &assists, &stats.LastHits, &stats.NetWorth, &stats.HeroDamage); err != nil {
+			return nil, fmt.Errorf("error scanning game player: %v", err)
+		}
+		if kills.Valid && deaths.Valid && assists.Valid {
+			stats.Kills, stats.Deaths, stats.Assists = int(kills.Int64), int(deaths.Int64), int(assists.Int64)
+			player.Stats = &stats
+		}
+		players = append(players, player)
+	}
+	return players, rows.Err()
+}
+
+func (s *PostgresGameStore) CreatePendingGame(game *models.PendingGame) error {
+	radiant, err := json.Marshal(game.RadiantPlayers)
+	if err != nil {
+		return fmt.Errorf("error encoding radiant players: %v", err)
+	}
+	dire, err := json.Marshal(game.DirePlayers)
+	if err != nil {
+		return fmt.Errorf("error encoding dire players: %v", err)
+	}
+
+	query := `
+		INSERT INTO pending_games (radiant_players, dire_players, source)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err = s.db.QueryRow(query, radiant, dire, game.Source).Scan(&game.ID, &game.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating pending game: %v", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresGameStore) ListPendingGames() ([]models.PendingGame, error) {
+	query := `
+		SELECT id, radiant_players, dire_players, source, created_at
+		FROM pending_games
+		ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pending games: %v", err)
+	}
+	defer rows.Close()
+
+	games := []models.PendingGame{}
+	for rows.Next() {
+		var game models.PendingGame
+		var radiant, dire []byte
+		if err := rows.Scan(&game.ID, &radiant, &dire, &game.Source, &game.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning pending game: %v", err)
+		}
+		if err := json.Unmarshal(radiant, &game.RadiantPlayers); err != nil {
+			return nil, fmt.Errorf("error decoding radiant players: %v", err)
+		}
+		if err := json.Unmarshal(dire, &game.DirePlayers); err != nil {
+			return nil, fmt.Errorf("error decoding dire players: %v", err)
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+func (s *PostgresGameStore) DeletePendingGameTx(tx *sql.Tx, id string) (bool, error) {
+	result, err := tx.Exec("DELETE FROM pending_games WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting pending game: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
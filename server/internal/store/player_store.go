@@ -2,12 +2,20 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
 
+var (
+	ErrDuplicateNickname     = errors.New("nickname is already taken")
+	ErrDuplicateSteamAccount = errors.New("steam account is already linked to another player")
+)
+
 type PlayerStore struct {
 	db *sql.DB
 }
@@ -17,7 +25,7 @@ func NewPlayerStore(db *sql.DB) *PlayerStore {
 }
 
 func (s *PlayerStore) GetAllPlayers() ([]Player, error) {
-	query := `SELECT id, nickname, COALESCE(games_played, ARRAY[]::UUID[]) FROM players`
+	query := `SELECT id, nickname, is_active, steam_account_id, COALESCE(games_played, ARRAY[]::UUID[]) FROM players`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		log.Printf("error querying players: %v", err)
@@ -29,7 +37,7 @@ func (s *PlayerStore) GetAllPlayers() ([]Player, error) {
 	for rows.Next() {
 		var player Player
 		var gamesPlayed []sql.NullString
-		if err := rows.Scan(&player.ID, &player.Nickname, pq.Array(&gamesPlayed)); err != nil {
+		if err := rows.Scan(&player.ID, &player.Nickname, &player.IsActive, &player.SteamAccountID, pq.Array(&gamesPlayed)); err != nil {
 			log.Printf("error scanning player: %v", err)
 			return nil, err
 		}
@@ -52,118 +60,401 @@ func (s *PlayerStore) GetAllPlayers() ([]Player, error) {
 }
 
 type Player struct {
-	ID          string   `json:"id"`
-	Nickname    string   `json:"nickname"`
-	GamesPlayed []string `json:"games_played"`
+	ID             string   `json:"id"`
+	Nickname       string   `json:"nickname"`
+	IsActive       bool     `json:"is_active"`
+	SteamAccountID *int64   `json:"steam_account_id,omitempty"`
+	GamesPlayed    []string `json:"games_played"`
 }
 
-type PlayerStats struct {
-	ID       string
-	Nickname string
-	Stats    string
+func (s *PlayerStore) GetPlayer(id string) (*Player, error) {
+	query := `SELECT id, nickname, is_active, steam_account_id, COALESCE(games_played, ARRAY[]::UUID[]) FROM players WHERE id = $1`
+
+	var player Player
+	var gamesPlayed []sql.NullString
+	err := s.db.QueryRow(query, id).Scan(&player.ID, &player.Nickname, &player.IsActive, &player.SteamAccountID, pq.Array(&gamesPlayed))
+	if err != nil {
+		return nil, err
+	}
+
+	player.GamesPlayed = make([]string, 0, len(gamesPlayed))
+	for _, g := range gamesPlayed {
+		if g.Valid {
+			player.GamesPlayed = append(player.GamesPlayed, g.String)
+		}
+	}
+
+	return &player, nil
 }
 
-func (s *PlayerStore) GetTopByWinRate() ([]PlayerStats, error) {
-	query := `
-		SELECT 
-			p.id,
-			p.nickname,
-			CAST(COUNT(CASE WHEN g.is_winner = true THEN 1 END) AS float) / CAST(COUNT(*) AS float) * 100 as winrate,
-			COUNT(CASE WHEN g.is_winner = true THEN 1 END) as wins,
-			COUNT(*) as total_games
-		FROM players p
-		JOIN game_players g ON p.id = g.player_id
-		GROUP BY p.id, p.nickname
-		HAVING COUNT(*) > 0
-		ORDER BY winrate DESC`
+// RenamePlayerTx refuses names used by other players, also as an alias, and drops
+// the player's own alias that the new nickname makes redundant
+func (s *PlayerStore) RenamePlayerTx(tx *sql.Tx, id, nickname string) error {
+	var taken bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM player_aliases WHERE LOWER(alias) = LOWER($1) AND player_id <> $2)", nickname, id).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("error checking aliases: %v", err)
+	}
+	if taken {
+		return ErrDuplicateNickname
+	}
 
-	rows, err := s.db.Query(query)
+	_, err = tx.Exec("DELETE FROM player_aliases WHERE player_id = $1 AND LOWER(alias) = LOWER($2)", id, nickname)
+	if err != nil {
+		return fmt.Errorf("error updating aliases: %v", err)
+	}
+
+	_, err = tx.Exec("UPDATE players SET nickname = $2 WHERE id = $1", id, nickname)
+	if isUniqueViolation(err) {
+		return ErrDuplicateNickname
+	}
+	if err != nil {
+		return fmt.Errorf("error renaming player: %v", err)
+	}
+	return nil
+}
+
+func (s *PlayerStore) SetPlayerActiveTx(tx *sql.Tx, id string, active bool) error {
+	_, err := tx.Exec("UPDATE players SET is_active = $2 WHERE id = $1", id, active)
+	if err != nil {
+		return fmt.Errorf("error updating player status: %v", err)
+	}
+	return nil
+}
+
+// SetSteamAccountTx links a Steam account to the player, nil removes the link
+func (s *PlayerStore) SetSteamAccountTx(tx *sql.Tx, id string, accountID *int64) error {
+	_, err := tx.Exec("UPDATE players SET steam_account_id = $2 WHERE id = $1", id, accountID)
+	if isUniqueViolation(err) {
+		return ErrDuplicateSteamAccount
+	}
+	if err != nil {
+		return fmt.Errorf("error updating steam account: %v", err)
+	}
+	return nil
+}
+
+// GetPlayersBySteamAccounts maps linked Steam account IDs to players
+func (s *PlayerStore) GetPlayersBySteamAccounts(accountIDs []int64) (map[int64]Player, error) {
+	query := `SELECT id, nickname, is_active, steam_account_id FROM players WHERE steam_account_id = ANY($1)`
+	rows, err := s.db.Query(query, pq.Array(accountIDs))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var stats []PlayerStats
+	players := make(map[int64]Player)
 	for rows.Next() {
-		var stat PlayerStats
-		var winrate float64
-		var wins, totalGames int
-		if err := rows.Scan(&stat.ID, &stat.Nickname, &winrate, &wins, &totalGames); err != nil {
+		var player Player
+		if err := rows.Scan(&player.ID, &player.Nickname, &player.IsActive, &player.SteamAccountID); err != nil {
 			return nil, err
 		}
-		stat.Stats = fmt.Sprintf("%.1f%% (%d/%d)", winrate, wins, totalGames)
-		stats = append(stats, stat)
+		players[*player.SteamAccountID] = player
 	}
-	return stats, rows.Err()
+	return players, rows.Err()
 }
 
-func (s *PlayerStore) GetTopByGames() ([]PlayerStats, error) {
+// MoveSteamAccountTx hands the source player's Steam account to the target unless it already has one
+func (s *PlayerStore) MoveSteamAccountTx(tx *sql.Tx, fromID, toID string) error {
+	var accountID sql.NullInt64
+	if err := tx.QueryRow("SELECT steam_account_id FROM players WHERE id = $1", fromID).Scan(&accountID); err != nil {
+		return fmt.Errorf("error reading steam account: %v", err)
+	}
+	if !accountID.Valid {
+		return nil
+	}
+
+	// Unlink first, the column is unique
+	if _, err := tx.Exec("UPDATE players SET steam_account_id = NULL WHERE id = $1", fromID); err != nil {
+		return fmt.Errorf("error unlinking steam account: %v", err)
+	}
+
+	_, err := tx.Exec("UPDATE players SET steam_account_id = COALESCE(steam_account_id, $2) WHERE id = $1", toID, accountID.Int64)
+	if err != nil {
+		return fmt.Errorf("error moving steam account: %v", err)
+	}
+	return nil
+}
+
+func (s *PlayerStore) BeginTx() (*sql.Tx, error) {
+	return s.db.Begin()
+}
+
+// CountSharedGamesTx returns how many games both players took part in
+func (s *PlayerStore) CountSharedGamesTx(tx *sql.Tx, playerA, playerB string) (int, error) {
 	query := `
-		SELECT 
-			p.id,
-			p.nickname,
-			COUNT(*) as games
-		FROM players p
-		JOIN game_players g ON p.id = g.player_id
-		GROUP BY p.id, p.nickname
-		ORDER BY games DESC`
+		SELECT COUNT(*)
+		FROM game_players a
+		JOIN game_players b ON a.game_id = b.game_id
+		WHERE a.player_id = $1 AND b.player_id = $2`
 
-	rows, err := s.db.Query(query)
+	var count int
+	if err := tx.QueryRow(query, playerA, playerB).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting shared games: %v", err)
+	}
+	return count, nil
+}
+
+func (s *PlayerStore) ReassignGamePlayersTx(tx *sql.Tx, fromID, toID string) error {
+	_, err := tx.Exec("UPDATE game_players SET player_id = $2 WHERE player_id = $1", fromID, toID)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("error reassigning game players: %v", err)
+	}
+	return nil
+}
+
+// RebuildGamesPlayedTx recomputes games_played from game_players in chronological order
+func (s *PlayerStore) RebuildGamesPlayedTx(tx *sql.Tx, playerID string) error {
+	query := `
+		UPDATE players
+		SET games_played = ARRAY(
+			SELECT gp.game_id
+			FROM game_players gp
+			JOIN games g ON g.id = gp.game_id
+			WHERE gp.player_id = $1
+			ORDER BY g.timestamp, g.id
+		)
+		WHERE id = $1`
+
+	_, err := tx.Exec(query, playerID)
+	if err != nil {
+		return fmt.Errorf("error rebuilding games played: %v", err)
+	}
+	return nil
+}
+
+func (s *PlayerStore) DeletePlayerTx(tx *sql.Tx, id string) error {
+	_, err := tx.Exec("DELETE FROM players WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting player: %v", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+type PlayerName struct {
+	PlayerID string
+	Name     string
+	IsAlias  bool
+}
+
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// listPlayerNames returns every nickname and alias so callers can run fuzzy matching over them
+func listPlayerNames(q querier) ([]PlayerName, error) {
+	query := `
+		SELECT id, nickname, false FROM players
+		UNION ALL
+		SELECT player_id, alias, true FROM player_aliases`
+
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying player names: %v", err)
 	}
 	defer rows.Close()
 
-	var stats []PlayerStats
+	var names []PlayerName
 	for rows.Next() {
-		var stat PlayerStats
-		var games int
-		if err := rows.Scan(&stat.ID, &stat.Nickname, &games); err != nil {
-			return nil, err
+		var name PlayerName
+		if err := rows.Scan(&name.PlayerID, &name.Name, &name.IsAlias); err != nil {
+			return nil, fmt.Errorf("error scanning player name: %v", err)
 		}
-		stat.Stats = fmt.Sprintf("%d games", games)
-		stats = append(stats, stat)
+		names = append(names, name)
 	}
-	return stats, rows.Err()
+	return names, rows.Err()
+}
+
+func (s *PlayerStore) ListPlayerNames() ([]PlayerName, error) {
+	return listPlayerNames(s.db)
+}
+
+func (s *PlayerStore) GetAliases(playerID string) ([]string, error) {
+	rows, err := s.db.Query("SELECT alias FROM player_aliases WHERE player_id = $1 ORDER BY alias", playerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying aliases: %v", err)
+	}
+	defer rows.Close()
+
+	aliases := []string{}
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, fmt.Errorf("error scanning alias: %v", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+// AddAlias registers an alias unless it clashes with another player's nickname or alias
+func (s *PlayerStore) AddAlias(playerID, alias string) error {
+	var taken bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM players WHERE LOWER(nickname) = LOWER($1) AND id <> $2)", alias, playerID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("error checking nickname: %v", err)
+	}
+	if taken {
+		return ErrDuplicateNickname
+	}
+
+	_, err = s.db.Exec("INSERT INTO player_aliases (alias, player_id) VALUES ($1, $2)", alias, playerID)
+	if isUniqueViolation(err) {
+		return ErrDuplicateNickname
+	}
+	if err != nil {
+		return fmt.Errorf("error adding alias: %v", err)
+	}
+	return nil
 }
 
-func (s *PlayerStore) GetTopCaptains() ([]PlayerStats, error) {
+func (s *PlayerStore) DeleteAlias(playerID, alias string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM player_aliases WHERE player_id = $1 AND LOWER(alias) = LOWER($2)", playerID, alias)
+	if err != nil {
+		return false, fmt.Errorf("error deleting alias: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// MoveAliasesTx re-points the source player's aliases to the target and keeps the source nickname as an alias
+func (s *PlayerStore) MoveAliasesTx(tx *sql.Tx, fromID, toID string) error {
+	_, err := tx.Exec("UPDATE player_aliases SET player_id = $2 WHERE player_id = $1", fromID, toID)
+	if err != nil {
+		return fmt.Errorf("error moving aliases: %v", err)
+	}
+
 	query := `
-		SELECT 
-			p.id,
-			p.nickname,
-			CAST(COUNT(CASE WHEN g.is_winner = true THEN 1 END) AS float) / CAST(COUNT(*) AS float) * 100 as winrate,
-			COUNT(CASE WHEN g.is_winner = true THEN 1 END) as wins,
-			COUNT(*) as total_games
-		FROM players p
-		JOIN game_players g ON p.id = g.player_id
-		WHERE g.is_captain = true
-		GROUP BY p.id, p.nickname
-		HAVING COUNT(*) > 0
-		ORDER BY winrate DESC`
+		INSERT INTO player_aliases (alias, player_id)
+		SELECT nickname, $2 FROM players WHERE id = $1
+		ON CONFLICT DO NOTHING`
 
-	rows, err := s.db.Query(query)
+	_, err = tx.Exec(query, fromID, toID)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("error adding merged nickname as alias: %v", err)
+	}
+	return nil
+}
+
+type RoleStats struct {
+	PlayerID string
+	Role     string
+	Games    int
+	Wins     int
+}
+
+// GetRoleStats returns games and wins per role for the given players
+func (s *PlayerStore) GetRoleStats(playerIDs []string) ([]RoleStats, error) {
+	query := `
+		SELECT player_id, role, COUNT(*), COUNT(CASE WHEN is_winner = true THEN 1 END)
+		FROM game_players
+		WHERE player_id = ANY($1)
+		GROUP BY player_id, role`
+
+	rows, err := s.db.Query(query, pq.Array(playerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying role stats: %v", err)
 	}
 	defer rows.Close()
 
-	var stats []PlayerStats
+	var stats []RoleStats
 	for rows.Next() {
-		var stat PlayerStats
-		var winrate float64
-		var wins, totalGames int
-		if err := rows.Scan(&stat.ID, &stat.Nickname, &winrate, &wins, &totalGames); err != nil {
-			return nil, err
+		var stat RoleStats
+		if err := rows.Scan(&stat.PlayerID, &stat.Role, &stat.Games, &stat.Wins); err != nil {
+			return nil, fmt.Errorf("error scanning role stats: %v", err)
 		}
-		stat.Stats = fmt.Sprintf("%.1f%% (%d/%d)", winrate, wins, totalGames)
 		stats = append(stats, stat)
 	}
 	return stats, rows.Err()
 }
 
-func (s *PlayerStore) GetTopByRole(role string) ([]PlayerStats, error) {
-	query := `
+type PlayerStats struct {
+	ID       string  `json:"id"`
+	Nickname string  `json:"nickname"`
+	Games    int     `json:"games"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+}
+
+type LeaderboardSort string
+
+const (
+	SortByWinRate LeaderboardSort = "winrate"
+	SortByGames   LeaderboardSort = "games"
+)
+
+type LeaderboardFilter struct {
+	// Empty SeasonID covers the whole history
+	SeasonID    string
+	Role        string
+	CaptainOnly bool
+	Side        string
+	From        *time.Time
+	To          *time.Time
+	MinGames    int
+	// Limit of zero returns every player
+	Limit int
+}
+
+// where builds the WHERE clause for players "p", game_players "g" and games "gm"
+func (f LeaderboardFilter) where() (string, []interface{}) {
+	conds := []string{"p.is_active = true"}
+	var args []interface{}
+
+	if f.SeasonID != "" {
+		args = append(args, f.SeasonID)
+		conds = append(conds, fmt.Sprintf("gm.season_id = $%d", len(args)))
+	}
+	if f.Role != "" {
+		args = append(args, f.Role)
+		conds = append(conds, fmt.Sprintf("g.role = $%d", len(args)))
+	}
+	if f.CaptainOnly {
+		conds = append(conds, "g.is_captain = true")
+	}
+	if f.Side != "" {
+		args = append(args, f.Side)
+		conds = append(conds, fmt.Sprintf("g.team = $%d", len(args)))
+	}
+	if f.From != nil {
+		args = append(args, *f.From)
+		conds = append(conds, fmt.Sprintf("gm.timestamp >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, *f.To)
+		conds = append(conds, fmt.Sprintf("gm.timestamp <= $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *PlayerStore) GetLeaderboard(sortBy LeaderboardSort, filter LeaderboardFilter) ([]PlayerStats, error) {
+	where, args := filter.where()
+
+	orderBy := "winrate DESC, total_games DESC, p.nickname"
+	if sortBy == SortByGames {
+		orderBy = "total_games DESC, winrate DESC, p.nickname"
+	}
+
+	args = append(args, max(filter.MinGames, 1))
+	having := fmt.Sprintf("HAVING COUNT(*) >= $%d", len(args))
+
+	limit := ""
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		limit = fmt.Sprintf("LIMIT $%d", len(args))
+	}
+
+	query := fmt.Sprintf(`
 		SELECT 
 			p.id,
 			p.nickname,
@@ -172,26 +463,26 @@ func (s *PlayerStore) GetTopByRole(role string) ([]PlayerStats, error) {
 			COUNT(*) as total_games
 		FROM players p
 		JOIN game_players g ON p.id = g.player_id
-		WHERE g.role = $1
+		JOIN games gm ON gm.id = g.game_id
+		%s
 		GROUP BY p.id, p.nickname
-		HAVING COUNT(*) > 0
-		ORDER BY winrate DESC`
+		%s
+		ORDER BY %s
+		%s`, where, having, orderBy, limit)
 
-	rows, err := s.db.Query(query, role)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var stats []PlayerStats
+	stats := []PlayerStats{}
 	for rows.Next() {
 		var stat PlayerStats
-		var winrate float64
-		var wins, totalGames int
-		if err := rows.Scan(&stat.ID, &stat.Nickname, &winrate, &wins, &totalGames); err != nil {
+		if err := rows.Scan(&stat.ID, &stat.Nickname, &stat.WinRate, &stat.Wins, &stat.Games); err != nil {
 			return nil, err
 		}
-		stat.Stats = fmt.Sprintf("%.1f%% (%d/%d)", winrate, wins, totalGames)
+		stat.Losses = stat.Games - stat.Wins
 		stats = append(stats, stat)
 	}
 	return stats, rows.Err()
@@ -211,13 +502,11 @@ func (s *PlayerStore) GetPlayerStats(playerID string) (PlayerStats, error) {
 		GROUP BY p.id, p.nickname`
 
 	var stat PlayerStats
-	var winrate float64
-	var wins, totalGames int
-	err := s.db.QueryRow(query, playerID).Scan(&stat.ID, &stat.Nickname, &winrate, &wins, &totalGames)
+	err := s.db.QueryRow(query, playerID).Scan(&stat.ID, &stat.Nickname, &stat.WinRate, &stat.Wins, &stat.Games)
 	if err != nil {
 		return PlayerStats{}, err
 	}
 
-	stat.Stats = fmt.Sprintf("%.1f%% (%d/%d)", winrate, wins, totalGames)
+	stat.Losses = stat.Games - stat.Wins
 	return stat, nil
 }
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type RatingStore struct {
+	db *sql.DB
+}
+
+func NewRatingStore(db *sql.DB) *RatingStore {
+	return &RatingStore{db: db}
+}
+
+type Rating struct {
+	PlayerID   string  `json:"player_id"`
+	Nickname   string  `json:"nickname,omitempty"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	Games      int     `json:"games"`
+}
+
+type RatingHistoryEntry struct {
+	GameID    string    `json:"game_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Team      string    `json:"team"`
+	IsWinner  bool      `json:"is_winner"`
+	Before    float64   `json:"before"`
+	After     float64   `json:"after"`
+	Delta     float64   `json:"delta"`
+}
+
+func (s *RatingStore) BeginTx() (*sql.Tx, error) {
+	return s.db.Begin()
+}
+
+// LockRatingsTx serializes rating writes so concurrent games never read stale ratings
+func (s *RatingStore) LockRatingsTx(tx *sql.Tx) error {
+	_, err := tx.Exec("LOCK TABLE player_ratings IN SHARE ROW EXCLUSIVE MODE")
+	if err != nil {
+		return fmt.Errorf("error locking ratings: %v", err)
+	}
+	return nil
+}
+
+func (s *RatingStore) GetRatingsTx(tx *sql.Tx, playerIDs []string) (map[string]Rating, error) {
+	return getRatings(tx, playerIDs)
+}
+
+func (s *RatingStore) GetRatings(playerIDs []string) (map[string]Rating, error) {
+	return getRatings(s.db, playerIDs)
+}
+
+func getRatings(q querier, playerIDs []string) (map[string]Rating, error) {
+	query := `
+		SELECT player_id, rating, deviation, volatility, games
+		FROM player_ratings
+		WHERE player_id = ANY($1)`
+
+	rows, err := q.Query(query, pq.Array(playerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying ratings: %v", err)
+	}
+	defer rows.Close()
+
+	ratings := make(map[string]Rating)
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.PlayerID, &r.Rating, &r.Deviation, &r.Volatility, &r.Games); err != nil {
+			return nil, fmt.Errorf("error scanning rating: %v", err)
+		}
+		ratings[r.PlayerID] = r
+	}
+	return ratings, rows.Err()
+}
+
+func (s *RatingStore) SaveRatingsTx(tx *sql.Tx, ratings []Rating) error {
+	query := `
+		INSERT INTO player_ratings (player_id, rating, deviation, volatility, games, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (player_id) DO UPDATE
+		SET rating = EXCLUDED.rating,
+			deviation = EXCLUDED.deviation,
+			volatility = EXCLUDED.volatility,
+			games = EXCLUDED.games,
+			updated_at = EXCLUDED.updated_at`
+
+	for _, r := range ratings {
+		_, err := tx.Exec(query, r.PlayerID, r.Rating, r.Deviation, r.Volatility, r.Games)
+		if err != nil {
+			return fmt.Errorf("error saving rating: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveGameRatingsTx stores each player's rating before and after the given game
+func (s *RatingStore) SaveGameRatingsTx(tx *sql.Tx, gameID string, playerID string, before, after float64) error {
+	query := `
+		UPDATE game_players
+		SET rating_before = $3, rating_after = $4
+		WHERE game_id = $1 AND player_id = $2`
+
+	_, err := tx.Exec(query, gameID, playerID, before, after)
+	if err != nil {
+		return fmt.Errorf("error saving game rating: %v", err)
+	}
+	return nil
+}
+
+func (s *RatingStore) GetRatingHistory(playerID string) ([]RatingHistoryEntry, error) {
+	query := `
+		SELECT gp.game_id, g.timestamp, gp.team, gp.is_winner, gp.rating_before, gp.rating_after
+		FROM game_players gp
+		JOIN games g ON g.id = gp.game_id
+		WHERE gp.player_id = $1 AND gp.rating_before IS NOT NULL AND gp.rating_after IS NOT NULL
+		ORDER BY g.timestamp, g.id`
+
+	rows, err := s.db.Query(query, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []RatingHistoryEntry{}
+	for rows.Next() {
+		var e RatingHistoryEntry
+		if err := rows.Scan(&e.GameID, &e.Timestamp, &e.Team, &e.IsWinner, &e.Before, &e.After); err != nil {
+			return nil, err
+		}
+		e.Delta = e.After - e.Before
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
+
+func (s *RatingStore) IsEmpty() (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM player_ratings)").Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking ratings: %v", err)
+	}
+	return !exists, nil
+}
+
+func (s *RatingStore) DeleteAllRatingsTx(tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM player_ratings")
+	if err != nil {
+		return fmt.Errorf("error deleting ratings: %v", err)
+	}
+	return nil
+}
+
+// GetGameHistoryTx returns every game_players row ordered chronologically by game
+func (s *RatingStore) GetGameHistoryTx(tx *sql.Tx) ([]GamePlayer, error) {
+	query := `
+		SELECT gp.game_id, gp.player_id, gp.team, gp.role, gp.is_captain, gp.is_winner
+		FROM game_players gp
+		JOIN games g ON g.id = gp.game_id
+		ORDER BY g.timestamp, g.id`
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying game history: %v", err)
+	}
+	defer rows.Close()
+
+	var players []GamePlayer
+	for rows.Next() {
+		var p GamePlayer
+		if err := rows.Scan(&p.GameID, &p.PlayerID, &p.Team, &p.Role, &p.IsCaptain, &p.IsWinner); err != nil {
+			return nil, fmt.Errorf("error scanning game history: %v", err)
+		}
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+// GetTopRatings ranks active players by their current rating, or with a seasonID
+// by their rating after their last game of that season and the games they
+// played in it. Deviation and volatility are only kept for current ratings.
+func (s *RatingStore) GetTopRatings(seasonID string, minGames, limit int) ([]Rating, error) {
+	query := `
+		SELECT r.player_id, p.nickname, r.rating, r.deviation, r.volatility, r.games
+		FROM player_ratings r
+		JOIN players p ON p.id = r.player_id
+		WHERE p.is_active = true AND r.games >= $1
+		ORDER BY r.rating DESC, r.deviation
+		LIMIT $2`
+	args := []interface{}{minGames, limit}
+
+	if seasonID != "" {
+		query = `
+			SELECT s.player_id, p.nickname, s.rating_after, 0, 0, s.games
+			FROM (
+				SELECT gp.player_id, gp.rating_after,
+					COUNT(*) OVER (PARTITION BY gp.player_id) AS games,
+					ROW_NUMBER() OVER (PARTITION BY gp.player_id ORDER BY g.timestamp DESC, g.id DESC) AS n
+				FROM game_players gp
+				JOIN games g ON g.id = gp.game_id
+				WHERE g.season_id = $3 AND gp.rating_after IS NOT NULL
+			) s
+			JOIN players p ON p.id = s.player_id
+			WHERE s.n = 1 AND p.is_active = true AND s.games >= $1
+			ORDER BY s.rating_after DESC, p.nickname
+			LIMIT $2`
+		args = append(args, seasonID)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []Rating{}
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.PlayerID, &r.Nickname, &r.Rating, &r.Deviation, &r.Volatility, &r.Games); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type Season struct {
+	ID        string     `json:"id"`
+	Number    int        `json:"number"`
+	Name      string     `json:"name"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+type SeasonStanding struct {
+	Position int     `json:"position"`
+	PlayerID string  `json:"player_id"`
+	Nickname string  `json:"nickname"`
+	Games    int     `json:"games"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+}
+
+type SeasonStore struct {
+	db *sql.DB
+}
+
+func NewSeasonStore(db *sql.DB) *SeasonStore {
+	return &SeasonStore{db: db}
+}
+
+func (s *SeasonStore) BeginTx() (*sql.Tx, error) {
+	return s.db.Begin()
+}
+
+const seasonColumns = "id, number, name, started_at, ended_at"
+
+func scanSeason(row interface{ Scan(...interface{}) error }) (*Season, error) {
+	var season Season
+	if err := row.Scan(&season.ID, &season.Number, &season.Name, &season.StartedAt, &season.EndedAt); err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (s *SeasonStore) ListSeasons() ([]Season, error) {
+	rows, err := s.db.Query("SELECT " + seasonColumns + " FROM seasons ORDER BY number DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []Season{}
+	for rows.Next() {
+		season, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, *season)
+	}
+	return seasons, rows.Err()
+}
+
+// GetSeason returns sql.ErrNoRows if the season does not exist
+func (s *SeasonStore) GetSeason(id string) (*Season, error) {
+	return scanSeason(s.db.QueryRow("SELECT "+seasonColumns+" FROM seasons WHERE id = $1", id))
+}
+
+func (s *SeasonStore) GetSeasonByNumber(number int) (*Season, error) {
+	return scanSeason(s.db.QueryRow("SELECT "+seasonColumns+" FROM seasons WHERE number = $1", number))
+}
+
+func (s *SeasonStore) GetActiveSeason() (*Season, error) {
+	return scanSeason(s.db.QueryRow("SELECT " + seasonColumns + " FROM seasons WHERE ended_at IS NULL"))
+}
+
+// LockActiveSeasonTx locks the open season so no game can be assigned to it while it is being closed
+func (s *SeasonStore) LockActiveSeasonTx(tx *sql.Tx) (*Season, error) {
+	return scanSeason(tx.QueryRow("SELECT " + seasonColumns + " FROM seasons WHERE ended_at IS NULL FOR UPDATE"))
+}
+
+func (s *SeasonStore) EndSeasonTx(tx *sql.Tx, season *Season) error {
+	err := tx.QueryRow("UPDATE seasons SET ended_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING ended_at", season.ID).
+		Scan(&season.EndedAt)
+	if err != nil {
+		return fmt.Errorf("error ending season: %v", err)
+	}
+	return nil
+}
+
+func (s *SeasonStore) CreateSeasonTx(tx *sql.Tx, number int, name string) (*Season, error) {
+	query := `
+		INSERT INTO seasons (number, name)
+		VALUES ($1, $2)
+		RETURNING ` + seasonColumns
+
+	season, err := scanSeason(tx.QueryRow(query, number, name))
+	if err != nil {
+		return nil, fmt.Errorf("error creating season: %v", err)
+	}
+	return season, nil
+}
+
+// archiveStandingsQuery stores the win rate standings of every player who played in season $1
+const archiveStandingsQuery = `
+		INSERT INTO season_standings (season_id, position, player_id, nickname, games, wins, losses, win_rate)
+		SELECT
+			$1,
+			ROW_NUMBER() OVER (ORDER BY win_rate DESC, games DESC, nickname),
+			player_id, nickname, games, wins, games - wins, win_rate
+		FROM (
+			SELECT
+				p.id AS player_id,
+				p.nickname,
+				COUNT(*) AS games,
+				COUNT(CASE WHEN g.is_winner = true THEN 1 END) AS wins,
+				CAST(COUNT(CASE WHEN g.is_winner = true THEN 1 END) AS float) / CAST(COUNT(*) AS float) * 100 AS win_rate
+			FROM players p
+			JOIN game_players g ON p.id = g.player_id
+			JOIN games gm ON gm.id = g.game_id
+			WHERE gm.season_id = $1
+			GROUP BY p.id, p.nickname
+		) stats`
+
+// ArchiveStandingsTx stores the final win rate standings of every player who played in the season
+func (s *SeasonStore) ArchiveStandingsTx(tx *sql.Tx, seasonID string) error {
+	if _, err := tx.Exec(archiveStandingsQuery, seasonID); err != nil {
+		return fmt.Errorf("error archiving standings: %v", err)
+	}
+	return nil
+}
+
+func (s *SeasonStore) GetStandings(seasonID string) ([]SeasonStanding, error) {
+	query := `
+		SELECT position, player_id, nickname, games, wins, losses, win_rate
+		FROM season_standings
+		WHERE season_id = $1
+		ORDER BY position`
+
+	rows, err := s.db.Query(query, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	standings := []SeasonStanding{}
+	for rows.Next() {
+		var st SeasonStanding
+		if err := rows.Scan(&st.Position, &st.PlayerID, &st.Nickname, &st.Games, &st.Wins, &st.Losses, &st.WinRate); err != nil {
+			return nil, err
+		}
+		standings = append(standings, st)
+	}
+	return standings, rows.Err()
+}
@@ -0,0 +1,504 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type StatsStore struct {
+	db *sql.DB
+}
+
+func NewStatsStore(db *sql.DB) *StatsStore {
+	return &StatsStore{db: db}
+}
+
+type DuoStats struct {
+	PlayerID        string  `json:"player_id"`
+	Nickname        string  `json:"nickname"`
+	PartnerID       string  `json:"partner_id"`
+	PartnerNickname string  `json:"partner_nickname"`
+	Games           int     `json:"games"`
+	Wins            int     `json:"wins"`
+	Losses          int     `json:"losses"`
+	WinRate         float64 `json:"win_rate"`
+}
+
+// GetDuoStats returns the record of every pair of active players who were on the
+// same team at least minGames times. With a playerID only that player's pairs
+// are returned, with the player always first. An empty seasonID covers the whole history.
+func (s *StatsStore) GetDuoStats(playerID, seasonID string, minGames int) ([]DuoStats, error) {
+	args := []interface{}{max(minGames, 1), seasonID}
+	pairCondition := "a.player_id < b.player_id"
+	if playerID != "" {
+		pairCondition = "a.player_id = $3 AND b.player_id <> a.player_id"
+		args = append(args, playerID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT 
+			a.player_id,
+			pa.nickname,
+			b.player_id,
+			pb.nickname,
+			COUNT(*) as games,
+			COUNT(CASE WHEN a.is_winner = true THEN 1 END) as wins
+		FROM game_players a
+		JOIN game_players b ON a.game_id = b.game_id AND a.team = b.team
+		JOIN players pa ON pa.id = a.player_id
+		JOIN players pb ON pb.id = b.player_id
+		JOIN games gm ON gm.id = a.game_id
+		WHERE %s AND pa.is_active = true AND pb.is_active = true AND ($2 = '' OR gm.season_id::text = $2)
+		GROUP BY a.player_id, pa.nickname, b.player_id, pb.nickname
+		HAVING COUNT(*) >= $1
+		ORDER BY CAST(COUNT(CASE WHEN a.is_winner = true THEN 1 END) AS float) / CAST(COUNT(*) AS float) DESC, games DESC`, pairCondition)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []DuoStats{}
+	for rows.Next() {
+		var stat DuoStats
+		if err := rows.Scan(&stat.PlayerID, &stat.Nickname, &stat.PartnerID, &stat.PartnerNickname, &stat.Games, &stat.Wins); err != nil {
+			return nil, err
+		}
+		stat.Losses = stat.Games - stat.Wins
+		stat.WinRate = float64(stat.Wins) / float64(stat.Games) * 100
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+type HeadToHead struct {
+	PlayerAID     string `json:"player_a_id"`
+	PlayerANick   string `json:"player_a_nickname"`
+	PlayerBID     string `json:"player_b_id"`
+	PlayerBNick   string `json:"player_b_nickname"`
+	GamesAgainst  int    `json:"games_against"`
+	WinsA         int    `json:"wins_a"`
+	WinsB         int    `json:"wins_b"`
+	GamesTogether int    `json:"games_together"`
+	WinsTogether  int    `json:"wins_together"`
+}
+
+// GetHeadToHead returns sql.ErrNoRows if either player does not exist
+func (s *StatsStore) GetHeadToHead(playerA, playerB string) (*HeadToHead, error) {
+	query := `
+		SELECT 
+			pa.nickname,
+			pb.nickname,
+			COUNT(CASE WHEN a.team <> b.team THEN 1 END) as games_against,
+			COUNT(CASE WHEN a.team <> b.team AND a.is_winner = true THEN 1 END) as wins_a,
+			COUNT(CASE WHEN a.team <> b.team AND b.is_winner = true THEN 1 END) as wins_b,
+			COUNT(CASE WHEN a.team = b.team THEN 1 END) as games_together,
+			COUNT(CASE WHEN a.team = b.team AND a.is_winner = true THEN 1 END) as wins_together
+		FROM players pa
+		CROSS JOIN players pb
+		LEFT JOIN game_players a ON a.player_id = pa.id
+		LEFT JOIN game_players b ON b.game_id = a.game_id AND b.player_id = pb.id
+		WHERE pa.id = $1 AND pb.id = $2
+		GROUP BY pa.nickname, pb.nickname`
+
+	h2h := HeadToHead{PlayerAID: playerA, PlayerBID: playerB}
+	err := s.db.QueryRow(query, playerA, playerB).Scan(&h2h.PlayerANick, &h2h.PlayerBNick,
+		&h2h.GamesAgainst, &h2h.WinsA, &h2h.WinsB, &h2h.GamesTogether, &h2h.WinsTogether)
+	if err != nil {
+		return nil, err
+	}
+	return &h2h, nil
+}
+
+type OpponentStats struct {
+	OpponentID string  `json:"opponent_id"`
+	Nickname   string  `json:"nickname"`
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Losses     int     `json:"losses"`
+	WinRate    float64 `json:"win_rate"`
+}
+
+// GetOpponentStats returns the player's record against every opponent they faced
+func (s *StatsStore) GetOpponentStats(playerID string) ([]OpponentStats, error) {
+	query := `
+		SELECT 
+			b.player_id,
+			p.nickname,
+			COUNT(*) as games,
+			COUNT(CASE WHEN a.is_winner = true THEN 1 END) as wins
+		FROM game_players a
+		JOIN game_players b ON a.game_id = b.game_id AND a.team <> b.team
+		JOIN players p ON p.id = b.player_id
+		WHERE a.player_id = $1
+		GROUP BY b.player_id, p.nickname`
+
+	rows, err := s.db.Query(query, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []OpponentStats{}
+	for rows.Next() {
+		var stat OpponentStats
+		if err := rows.Scan(&stat.OpponentID, &stat.Nickname, &stat.Games, &stat.Wins); err != nil {
+			return nil, err
+		}
+		stat.Losses = stat.Games - stat.Wins
+		stat.WinRate = float64(stat.Wins) / float64(stat.Games) * 100
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+type PlayerResult struct {
+	PlayerID string
+	Nickname string
+	GameID   string
+	IsWinner bool
+}
+
+// GetPlayerResults returns game results in chronological order, for the given
+// players or for every active player when playerIDs is empty. An empty seasonID
+// covers the whole history.
+func (s *StatsStore) GetPlayerResults(seasonID string, playerIDs []string) ([]PlayerResult, error) {
+	query := `
+		SELECT gp.player_id, p.nickname, gp.game_id, gp.is_winner
+		FROM game_players gp
+		JOIN games g ON g.id = gp.game_id
+		JOIN players p ON p.id = gp.player_id
+		WHERE ((cardinality($1::UUID[]) = 0 AND p.is_active = true) OR gp.player_id = ANY($1::UUID[]))
+			AND ($2 = '' OR g.season_id::text = $2)
+		ORDER BY g.timestamp, g.id`
+
+	rows, err := s.db.Query(query, pq.Array(playerIDs), seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []PlayerResult
+	for rows.Next() {
+		var r PlayerResult
+		if err := rows.Scan(&r.PlayerID, &r.Nickname, &r.GameID, &r.IsWinner); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+type DurationStats struct {
+	PlayerID   string  `json:"player_id"`
+	Nickname   string  `json:"nickname"`
+	Games      int     `json:"games"`
+	AvgMinutes float64 `json:"avg_minutes"`
+	ShortGames int     `json:"short_games"`
+	ShortWins  int     `json:"short_wins"`
+	LongGames  int     `json:"long_games"`
+	LongWins   int     `json:"long_wins"`
+}
+
+// GetDurationStats aggregates games with a known end time, split into games shorter
+// than splitMinutes and the rest. An empty playerID covers every active player.
+func (s *StatsStore) GetDurationStats(playerID, seasonID string, splitMinutes int) ([]DurationStats, error) {
+	query := `
+		SELECT
+			p.id,
+			p.nickname,
+			COUNT(*),
+			AVG(d.minutes),
+			COUNT(*) FILTER (WHERE d.minutes < $1),
+			COUNT(*) FILTER (WHERE d.minutes < $1 AND g.is_winner = true),
+			COUNT(*) FILTER (WHERE d.minutes >= $1),
+			COUNT(*) FILTER (WHERE d.minutes >= $1 AND g.is_winner = true)
+		FROM players p
+		JOIN game_players g ON g.player_id = p.id
+		JOIN games gm ON gm.id = g.game_id
+		CROSS JOIN LATERAL (SELECT EXTRACT(EPOCH FROM gm.end_time - gm.timestamp) / 60 AS minutes) d
+		WHERE gm.end_time IS NOT NULL
+			AND (($2 = '' AND p.is_active = true) OR p.id::text = $2)
+			AND ($3 = '' OR gm.season_id::text = $3)
+		GROUP BY p.id, p.nickname
+		ORDER BY AVG(d.minutes) DESC, p.nickname`
+
+	rows, err := s.db.Query(query, splitMinutes, playerID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []DurationStats{}
+	for rows.Next() {
+		var d DurationStats
+		if err := rows.Scan(&d.PlayerID, &d.Nickname, &d.Games, &d.AvgMinutes, &d.ShortGames, &d.ShortWins, &d.LongGames, &d.LongWins); err != nil {
+			return nil, err
+		}
+		stats = append(stats, d)
+	}
+	return stats, rows.Err()
+}
+
+type HeroStats struct {
+	HeroID int
+	Games  int
+	Wins   int
+}
+
+// GetHeroStats counts games and wins per hero for one player, or everyone when
+// playerID is empty. An empty seasonID covers the whole history.
+func (s *StatsStore) GetHeroStats(playerID, seasonID string) ([]HeroStats, error) {
+	query := `
+		SELECT g.hero_id, COUNT(*), COUNT(CASE WHEN g.is_winner = true THEN 1 END)
+		FROM game_players g
+		JOIN games gm ON gm.id = g.game_id
+		WHERE g.hero_id IS NOT NULL AND ($1 = '' OR g.player_id::text = $1)
+			AND ($2 = '' OR gm.season_id::text = $2)
+		GROUP BY g.hero_id
+		ORDER BY COUNT(*) DESC, g.hero_id`
+
+	rows, err := s.db.Query(query, playerID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []HeroStats
+	for rows.Next() {
+		var h HeroStats
+		if err := rows.Scan(&h.HeroID, &h.Games, &h.Wins); err != nil {
+			return nil, err
+		}
+		stats = append(stats, h)
+	}
+	return stats, rows.Err()
+}
+
+type HeroPlayerStats struct {
+	PlayerID string  `json:"player_id"`
+	Nickname string  `json:"nickname"`
+	Games    int     `json:"games"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+}
+
+// GetHeroPlayers returns active players who played a hero, most games first,
+// optionally in one season only
+func (s *StatsStore) GetHeroPlayers(heroID int, seasonID string) ([]HeroPlayerStats, error) {
+	query := `
+		SELECT
+			p.id,
+			p.nickname,
+			COUNT(*) AS games,
+			COUNT(CASE WHEN g.is_winner = true THEN 1 END) AS wins,
+			CAST(COUNT(CASE WHEN g.is_winner = true THEN 1 END) AS float) / CAST(COUNT(*) AS float) * 100 AS winrate
+		FROM players p
+		JOIN game_players g ON g.player_id = p.id
+		JOIN games gm ON gm.id = g.game_id
+		WHERE g.hero_id = $1 AND p.is_active = true AND ($2 = '' OR gm.season_id::text = $2)
+		GROUP BY p.id, p.nickname
+		ORDER BY games DESC, winrate DESC, p.nickname`
+
+	rows, err := s.db.Query(query, heroID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []HeroPlayerStats{}
+	for rows.Next() {
+		var p HeroPlayerStats
+		if err := rows.Scan(&p.PlayerID, &p.Nickname, &p.Games, &p.Wins, &p.WinRate); err != nil {
+			return nil, err
+		}
+		p.Losses = p.Games - p.Wins
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+type PerformanceStats struct {
+	PlayerID      string   `json:"player_id"`
+	Nickname      string   `json:"nickname"`
+	Games         int      `json:"games"`
+	AvgKills      float64  `json:"avg_kills"`
+	AvgDeaths     float64  `json:"avg_deaths"`
+	AvgAssists    float64  `json:"avg_assists"`
+	KDA           float64  `json:"kda"`
+	AvgLastHits   *float64 `json:"avg_last_hits,omitempty"`
+	AvgNetWorth   *float64 `json:"avg_net_worth,omitempty"`
+	AvgHeroDamage *float64 `json:"avg_hero_damage,omitempty"`
+}
+
+// GetKDALeaderboard ranks active players by (kills + assists) / deaths over games
+// with recorded stats, optionally on one role or in one season only
+func (s *StatsStore) GetKDALeaderboard(role, seasonID string, minGames, limit int) ([]PerformanceStats, error) {
+	query := `
+		SELECT
+			p.id,
+			p.nickname,
+			COUNT(*) AS games,
+			AVG(g.kills),
+			AVG(g.deaths),
+			AVG(g.assists),
+			CAST(SUM(g.kills + g.assists) AS float) / GREATEST(SUM(g.deaths), 1) AS kda,
+			AVG(g.last_hits),
+			AVG(g.net_worth),
+			AVG(g.hero_damage)
+		FROM players p
+		JOIN game_players g ON g.player_id = p.id
+		JOIN games gm ON gm.id = g.game_id
+		WHERE p.is_active = true AND g.kills IS NOT NULL AND ($1 = '' OR g.role = $1)
+			AND ($4 = '' OR gm.season_id::text = $4)
+		GROUP BY p.id, p.nickname
+		HAVING COUNT(*) >= $2
+		ORDER BY kda DESC, games DESC, p.nickname
+		LIMIT $3`
+
+	rows, err := s.db.Query(query, role, max(minGames, 1), limit, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []PerformanceStats{}
+	for rows.Next() {
+		var st PerformanceStats
+		if err := rows.Scan(&st.PlayerID, &st.Nickname, &st.Games, &st.AvgKills, &st.AvgDeaths, &st.AvgAssists, &st.KDA,
+			&st.AvgLastHits, &st.AvgNetWorth, &st.AvgHeroDamage); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// Columns of game_players that single-game records can be set on
+var RecordStats = []string{"kills", "deaths", "assists", "last_hits", "net_worth", "hero_damage"}
+
+type GameRecord struct {
+	GameID    string    `json:"game_id"`
+	Timestamp time.Time `json:"timestamp"`
+	PlayerID  string    `json:"player_id"`
+	Nickname  string    `json:"nickname"`
+	HeroID    *int      `json:"hero_id,omitempty"`
+	Value     int       `json:"value"`
+}
+
+// GetRecords returns the highest single-game values of a stat, which must be one of
+// RecordStats. An empty seasonID covers the whole history.
+func (s *StatsStore) GetRecords(stat, seasonID string, limit int) ([]GameRecord, error) {
+	valid := false
+	for _, name := range RecordStats {
+		if name == stat {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown stat: %s", stat)
+	}
+
+	// stat is one of the known column names, so it is safe to format into the query
+	query := fmt.Sprintf(`
+		SELECT gm.id, gm.timestamp, p.id, p.nickname, g.hero_id, g.%[1]s
+		FROM game_players g
+		JOIN games gm ON gm.id = g.game_id
+		JOIN players p ON p.id = g.player_id
+		WHERE g.%[1]s IS NOT NULL AND ($2 = '' OR gm.season_id::text = $2)
+		ORDER BY g.%[1]s DESC, gm.timestamp
+		LIMIT $1`, stat)
+
+	rows, err := s.db.Query(query, limit, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []GameRecord{}
+	for rows.Next() {
+		var r GameRecord
+		if err := rows.Scan(&r.GameID, &r.Timestamp, &r.PlayerID, &r.Nickname, &r.HeroID, &r.Value); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// PlayerSplit is a player's record in the games played with one team, role and captaincy
+type PlayerSplit struct {
+	Team      string
+	Role      string
+	IsCaptain bool
+	Games     int
+	Wins      int
+}
+
+// GetPlayerSplits groups a player's games by team, role and captaincy, so any of
+// those records can be summed up from the result
+func (s *StatsStore) GetPlayerSplits(playerID string) ([]PlayerSplit, error) {
+	query := `
+		SELECT team, role, is_captain, COUNT(*), COUNT(CASE WHEN is_winner = true THEN 1 END)
+		FROM game_players
+		WHERE player_id = $1
+		GROUP BY team, role, is_captain`
+
+	rows, err := s.db.Query(query, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var splits []PlayerSplit
+	for rows.Next() {
+		var split PlayerSplit
+		if err := rows.Scan(&split.Team, &split.Role, &split.IsCaptain, &split.Games, &split.Wins); err != nil {
+			return nil, err
+		}
+		splits = append(splits, split)
+	}
+	return splits, rows.Err()
+}
+
+type RecentGame struct {
+	GameID    string    `json:"game_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Team      string    `json:"team"`
+	Role      string    `json:"role"`
+	IsCaptain bool      `json:"is_captain"`
+	IsWinner  bool      `json:"is_winner"`
+	HeroID    *int      `json:"hero_id,omitempty"`
+}
+
+// GetRecentGames returns the last games of a player, newest first
+func (s *StatsStore) GetRecentGames(playerID string, limit int) ([]RecentGame, error) {
+	query := `
+		SELECT g.id, g.timestamp, gp.team, gp.role, gp.is_captain, gp.is_winner, gp.hero_id
+		FROM game_players gp
+		JOIN games g ON g.id = gp.game_id
+		WHERE gp.player_id = $1
+		ORDER BY g.timestamp DESC, g.id DESC
+		LIMIT $2`
+
+	rows, err := s.db.Query(query, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []RecentGame{}
+	for rows.Next() {
+		var g RecentGame
+		if err := rows.Scan(&g.GameID, &g.Timestamp, &g.Team, &g.Role, &g.IsCaptain, &g.IsWinner, &g.HeroID); err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
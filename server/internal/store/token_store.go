@@ -0,0 +1,100 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrDuplicateTokenName = errors.New("a token with this name already exists")
+
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type TokenStore struct {
+	db *sql.DB
+}
+
+func NewTokenStore(db *sql.DB) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+const tokenColumns = "id, name, scope, created_at, last_used_at, revoked_at"
+
+func scanToken(row interface{ Scan(...interface{}) error }) (*APIToken, error) {
+	var t APIToken
+	if err := row.Scan(&t.ID, &t.Name, &t.Scope, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *TokenStore) CreateToken(name, hash, scope string) (*APIToken, error) {
+	query := `
+		INSERT INTO api_tokens (name, token_hash, scope)
+		VALUES ($1, $2, $3)
+		RETURNING ` + tokenColumns
+	token, err := scanToken(s.db.QueryRow(query, name, hash, scope))
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateTokenName
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating token: %v", err)
+	}
+	return token, nil
+}
+
+// GetActiveTokenByHash returns sql.ErrNoRows for unknown and revoked tokens
+func (s *TokenStore) GetActiveTokenByHash(hash string) (*APIToken, error) {
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE token_hash = $1 AND revoked_at IS NULL`
+	return scanToken(s.db.QueryRow(query, hash))
+}
+
+func (s *TokenStore) ListTokens() ([]APIToken, error) {
+	rows, err := s.db.Query(`SELECT ` + tokenColumns + ` FROM api_tokens ORDER BY created_at, name`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tokens: %v", err)
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning token: %v", err)
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken revokes an active token by name, false if there was none
+func (s *TokenStore) RevokeToken(name string) (bool, error) {
+	result, err := s.db.Exec("UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE name = $1 AND revoked_at IS NULL", name)
+	if err != nil {
+		return false, fmt.Errorf("error revoking token: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error revoking token: %v", err)
+	}
+	return affected > 0, nil
+}
+
+// TouchToken records that a token was used, at most once a minute
+func (s *TokenStore) TouchToken(id string) error {
+	_, err := s.db.Exec(`
+		UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`, id)
+	if err != nil {
+		return fmt.Errorf("error updating token: %v", err)
+	}
+	return nil
+}
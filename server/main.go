@@ -3,7 +3,9 @@ package main
 import (
 	"database/sql"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -33,20 +35,24 @@ func main() {
 		log.Fatalf("Could not ping database: %v", err)
 	}
 
+	// Run CLI subcommand (e.g. `migrate status`) instead of the server
+	if len(os.Args) > 1 {
+		if err := runCommand(db, os.Args[1:]); err != nil {
+			log.Fatalf("Error running %s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Apply pending schema migrations
+	if err := applyMigrations(db); err != nil {
+		log.Fatalf("Error migrating database: %v", err)
+	}
+
 	// Initialize Gin router
 	r := gin.Default()
 
-	// Setup CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-		c.Next()
-	})
+	// Setup CORS middleware for the origins in CORS_ALLOWED_ORIGINS
+	r.Use(corsMiddleware(allowedOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"))))
 
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
@@ -69,3 +75,39 @@ func main() {
 		log.Fatalf("Error starting server: %v", err)
 	}
 }
+
+// Origins allowed when CORS_ALLOWED_ORIGINS is not set, the admin panel dev server
+const defaultAllowedOrigins = "http://localhost:5173"
+
+// allowedOrigins parses a comma-separated origin list, "*" allows any origin
+func allowedOrigins(value string) map[string]bool {
+	if strings.TrimSpace(value) == "" {
+		value = defaultAllowedOrigins
+	}
+	origins := make(map[string]bool)
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins[origin] = true
+		}
+	}
+	return origins
+}
+
+func corsMiddleware(origins map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		c.Writer.Header().Add("Vary", "Origin")
+		if origin != "" && (origins[origin] || origins["*"]) {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "WWW-Authenticate, Content-Disposition")
+		}
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"ymb-cloz/internal/handler"
 	"ymb-cloz/internal/service"
 	"ymb-cloz/internal/store"
@@ -16,17 +18,57 @@ import (
 
 func setupRoutes(r *gin.Engine, db *sql.DB) {
 	// Initialize dependencies
+	ratingStore := store.NewRatingStore(db)
+	ratingService := service.NewRatingService(ratingStore)
+
+	if err := ratingService.EnsureComputed(); err != nil {
+		log.Printf("Error computing initial ratings: %v", err)
+	}
+
 	gameStore := store.NewGameStore(db)
-	gameService := service.NewGameService(gameStore)
+	gameService := service.NewGameService(gameStore, ratingService)
 	gameHandler := handler.NewGameHandler(gameService)
 
 	playerStore := store.NewPlayerStore(db)
-	playerService := service.NewPlayerService(playerStore)
-	playerHandler := handler.NewPlayerHandler(playerService)
+	playerService := service.NewPlayerService(playerStore, ratingService)
+	seasonStore := store.NewSeasonStore(db)
+	seasonService := service.NewSeasonService(seasonStore)
+	seasonHandler := handler.NewSeasonHandler(seasonService)
+	ratingHandler := handler.NewRatingHandler(ratingService, seasonService)
+
+	playerHandler := handler.NewPlayerHandler(playerService, seasonService)
+	notationHandler := handler.NewNotationHandler(playerService)
+
+	statsStore := store.NewStatsStore(db)
+	statsService := service.NewStatsService(statsStore)
+	statsHandler := handler.NewStatsHandler(statsService, playerService, seasonService)
+
+	balanceService := service.NewBalanceService(playerStore, ratingStore)
+	balanceHandler := handler.NewBalanceHandler(balanceService)
+
+	// OPENDOTA_URL can point at any OpenDota-compatible API, e.g. a local stub
+	matchImporter := service.NewMatchImporter(os.Getenv("OPENDOTA_URL"), os.Getenv("OPENDOTA_API_KEY"), playerStore, gameService)
+	importHandler := handler.NewImportHandler(matchImporter)
+
+	backupService := service.NewBackupService(store.NewBackupStore(db), ratingService)
+	backupHandler := handler.NewBackupHandler(backupService)
 
 	// Initialize Telegram bot
 	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
 
+	// TELEGRAM_ADMINS lists the numeric user IDs allowed to log in to the admin panel and record games in the bot
+	admins, err := service.NewAdminList(strings.Split(os.Getenv("TELEGRAM_ADMINS"), ","))
+	if err != nil {
+		log.Printf("Ignoring TELEGRAM_ADMINS entries: %v", err)
+	}
+	telegramAuth := service.NewTelegramAuth(botToken, admins, os.Getenv("SESSION_SECRET"))
+	authHandler := handler.NewAuthHandler(telegramAuth)
+	var sessions *service.TelegramAuth
+	if botToken != "" {
+		sessions = telegramAuth
+	}
+	authService := service.NewAuthService(store.NewTokenStore(db), sessions)
+
 	if botToken == "" {
 		log.Println("No Telegram bot token provided")
 	}
@@ -36,14 +78,70 @@ func setupRoutes(r *gin.Engine, db *sql.DB) {
 		if err != nil {
 			log.Printf("Error initializing Telegram bot: %v", err)
 		} else {
-			bot := bot.NewBot(tgBot, playerService)
+			conversationService := service.NewConversationService(store.NewConversationStore(db))
+			bot := bot.NewBot(tgBot, playerService, ratingService, balanceService, gameService, statsService, seasonService, matchImporter, conversationService, admins)
+
+			// Announce recorded games and rating changes in the group chat
+			if chatID, err := strconv.ParseInt(os.Getenv("TELEGRAM_CHAT_ID"), 10, 64); err == nil {
+				gameService.Subscribe(bot.AnnounceGame(chatID))
+			}
+
 			go bot.Start()
 		}
 	}
 
+	// Reads need a read or admin token unless API_PUBLIC_READ=true, writes always need an admin token
 	api := r.Group("/api")
+	api.POST("/auth/telegram", authHandler.TelegramLogin)
+
+	read := api.Group("")
+	if os.Getenv("API_PUBLIC_READ") != "true" {
+		read.Use(handler.RequireScope(authService, service.ScopeRead))
+	}
+	{
+		read.GET("/games", gameHandler.ListGames)
+		read.GET("/export/games.csv", gameHandler.ExportGamesCSV)
+		read.GET("/games/:id", gameHandler.GetGame)
+		read.GET("/pending-games", gameHandler.ListPendingGames)
+		read.GET("/players", playerHandler.GetAllPlayers)
+		read.GET("/players/:id/aliases", playerHandler.GetAliases)
+		read.GET("/players/:id/rating-history", ratingHandler.GetRatingHistory)
+		read.GET("/players/:id/profile", statsHandler.GetProfile)
+		read.GET("/players/:id/rivals", statsHandler.GetRivals)
+		read.GET("/players/:id/streaks", statsHandler.GetPlayerStreaks)
+		read.GET("/players/:id/heroes", statsHandler.GetHeroStats)
+		read.GET("/leaderboards/:kind", playerHandler.GetLeaderboard)
+		read.GET("/seasons", seasonHandler.ListSeasons)
+		read.GET("/seasons/:id/standings", seasonHandler.GetStandings)
+		read.GET("/ratings", ratingHandler.GetRatings)
+		read.POST("/balance", balanceHandler.Balance)
+		read.POST("/games/parse", notationHandler.ParseGame)
+		read.GET("/stats/duos", statsHandler.GetDuos)
+		read.GET("/stats/h2h", statsHandler.GetHeadToHead)
+		read.GET("/stats/streaks", statsHandler.GetStreaks)
+		read.GET("/stats/durations", statsHandler.GetDurations)
+		read.GET("/stats/heroes", statsHandler.GetHeroStats)
+		read.GET("/stats/kda", statsHandler.GetKDALeaderboard)
+		read.GET("/stats/records/:stat", statsHandler.GetRecords)
+		read.GET("/heroes", statsHandler.ListHeroes)
+		read.GET("/heroes/:hero", statsHandler.GetHero)
+	}
+
+	admin := api.Group("", handler.RequireScope(authService, service.ScopeAdmin))
 	{
-		api.POST("/games", gameHandler.CreateGame)
-		api.GET("/players", playerHandler.GetAllPlayers)
+		admin.POST("/games", gameHandler.CreateGame)
+		admin.POST("/games/import", importHandler.ImportMatch)
+		admin.POST("/import/games", gameHandler.ImportGamesCSV)
+		admin.PUT("/games/:id", gameHandler.UpdateGame)
+		admin.DELETE("/games/:id", gameHandler.DeleteGame)
+		admin.DELETE("/pending-games/:id", gameHandler.DeletePendingGame)
+		admin.PATCH("/players/:id", playerHandler.UpdatePlayer)
+		admin.POST("/players/:id/merge", playerHandler.MergePlayers)
+		admin.POST("/players/:id/aliases", playerHandler.AddAlias)
+		admin.DELETE("/players/:id/aliases/:alias", playerHandler.DeleteAlias)
+		admin.POST("/seasons/close", seasonHandler.CloseSeason)
+		admin.POST("/ratings/recompute", ratingHandler.Recompute)
+		admin.GET("/admin/backup", backupHandler.Backup)
+		admin.POST("/admin/restore", backupHandler.Restore)
 	}
 }